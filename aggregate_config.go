@@ -0,0 +1,226 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AggregateFunction is an enumeration of the SQL aggregate functions
+type AggregateFunction int
+
+const (
+	AGGREGATE_COUNT          AggregateFunction = iota // COUNT(*), or COUNT(field) if a Field is set
+	AGGREGATE_COUNT_DISTINCT                          // COUNT(DISTINCT field)
+	AGGREGATE_SUM                                     // SUM(field)
+	AGGREGATE_AVG                                     // AVG(field)
+	AGGREGATE_MIN                                     // MIN(field)
+	AGGREGATE_MAX                                     // MAX(field)
+)
+
+// Aggregate is the definition of a single aggregate of an AggregateConfig
+type Aggregate struct {
+	Function AggregateFunction
+	Field    string
+
+	// Alias is the key of the aggregate in AggregateRow.Values, which is
+	// also used to refer to the aggregate in Having and OrderBys.
+	//
+	// This defaults to the lower case function name, followed by an
+	// underscore and the Field (e.g. `sum_age`), or just `count` for a
+	// COUNT(*).
+	Alias string
+}
+
+// alias returns the Alias of the aggregate, or its default
+func (a Aggregate) alias() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	name := strings.ToLower(a.functionName())
+	if a.Function == AGGREGATE_COUNT_DISTINCT {
+		name = "count_distinct"
+	}
+	if a.Field == "" {
+		return name
+	}
+	return name + "_" + a.Field
+}
+
+// functionName returns the SQL function of the aggregate
+func (a Aggregate) functionName() string {
+	switch a.Function {
+	case AGGREGATE_SUM:
+		return "SUM"
+	case AGGREGATE_AVG:
+		return "AVG"
+	case AGGREGATE_MIN:
+		return "MIN"
+	case AGGREGATE_MAX:
+		return "MAX"
+	}
+	return "COUNT"
+}
+
+// toString converts an Aggregate to SQL, without its alias
+func (a Aggregate) toString(dialect Dialect) string {
+	if a.Function == AGGREGATE_COUNT && a.Field == "" {
+		return "COUNT(*)"
+	}
+	distinct := ""
+	if a.Function == AGGREGATE_COUNT_DISTINCT {
+		distinct = "DISTINCT "
+	}
+	return a.functionName() + "(" + distinct + dialect.QuoteIdentifier(a.Field) + ")"
+}
+
+// AggregateConfig is the configuration of a Model.Aggregate()
+type AggregateConfig struct {
+	Aggregates []Aggregate
+
+	// GroupBys are the names of the fields that the rows are grouped by
+	GroupBys []string
+
+	// Predicates filter the rows before they are grouped
+	Predicates []Predicate
+
+	// Having filters the groups, where each Field is the Alias of
+	// an Aggregate or one of the GroupBys
+	Having []Predicate
+
+	// OrderBys order the groups, where each Field is the Alias of
+	// an Aggregate or one of the GroupBys
+	OrderBys []OrderBy
+
+	// Limit is the most groups that are returned, where 0 returns all
+	// of the groups
+	Limit  int
+	Offset int
+}
+
+// AggregateRow is a single row of the result of a Model.Aggregate()
+//
+// Groups holds the value of each of the GroupBys, in the type of its field.
+// Values holds the value of each Aggregate, keyed by its Alias.  COUNTs are an
+// `int64`, SUMs + AVGs are a `float64` and MINs + MAXs are in the type of
+// their field.  A NULL value, such as the SUM of no rows, is held as nil.
+type AggregateRow struct {
+	Groups map[string]interface{}
+	Values map[string]interface{}
+}
+
+// value returns the value of the group or aggregate named name
+func (r AggregateRow) value(name string) interface{} {
+	if value, ok := r.Groups[name]; ok {
+		return value
+	}
+	return r.Values[name]
+}
+
+// validate returns an error in the event that the AggregateConfig does not
+// refer to the fields of config, or refers to an unknown alias
+//
+// This function will panic in the event that it encounters a malformed predicate
+func (c AggregateConfig) validate(config *Configuration) error {
+	if len(c.Aggregates) == 0 {
+		return errors.New("Aggregate requires at least one Aggregate")
+	}
+
+	// Group bys
+	names := make(map[string]bool)
+	for _, groupBy := range c.GroupBys {
+		if !containsField(config.Fields, groupBy) {
+			return invalidColumn("group", config, groupBy)
+		}
+		names[groupBy] = true
+	}
+
+	// Aggregates
+	for _, aggregate := range c.Aggregates {
+		if aggregate.Field == "" && aggregate.Function != AGGREGATE_COUNT {
+			return fmt.Errorf("The aggregate '%v' requires a Field", aggregate.alias())
+		}
+		if aggregate.Field != "" && !containsField(config.Fields, aggregate.Field) {
+			return invalidColumn("aggregate", config, aggregate.Field)
+		}
+		if names[aggregate.alias()] {
+			return fmt.Errorf("The alias '%v' is used more than once", aggregate.alias())
+		}
+		names[aggregate.alias()] = true
+	}
+
+	// Predicates, having + order bys
+	err := validatePredicates(config, c.Predicates)
+	if err != nil {
+		return err
+	}
+	err = validateHaving(config, names, c.Having)
+	if err != nil {
+		return err
+	}
+	for _, orderBy := range c.OrderBys {
+		if !names[orderBy.Field] {
+			return fmt.Errorf("Could not order the groups of table '%v' by the invalid alias '%v'",
+				config.TableName, orderBy.Field)
+		}
+	}
+	return nil
+}
+
+// validateHaving returns an error in the event that any of the having
+// predicates refer to a name that isn't in names
+//
+// This function will panic in the event that it encounters a malformed predicate
+func validateHaving(config *Configuration, names map[string]bool, having []Predicate) error {
+	for _, predicate := range having {
+		predicate.validate()
+		if predicate.isGroup() {
+			err := validateHaving(config, names, predicate.Predicates)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !names[predicate.Field] {
+			return fmt.Errorf("Could not filter the groups of table '%v' by the invalid alias '%v'",
+				config.TableName, predicate.Field)
+		}
+	}
+	return nil
+}
+
+// orderByString converts an OrderBy of the groups to SQL
+//
+// In the event the Dialect emulates `NULLS FIRST` or `NULLS LAST` with a
+// CASE, an OrderBy of the alias of an aggregate is written as the SQL of the
+// aggregate, as an alias can't be used inside of an expression.
+func (c AggregateConfig) orderByString(dialect Dialect, orderBy OrderBy) string {
+	if orderBy.Nulls != NULLS_DEFAULT && !dialect.NullsOrdering() {
+		for _, aggregate := range c.Aggregates {
+			if aggregate.alias() == orderBy.Field {
+				return orderBy.expressionToString(dialect, aggregate.toString(dialect))
+			}
+		}
+	}
+	return orderBy.toString(dialect)
+}
+
+// having returns a copy of the having predicates, where every Field that is
+// the alias of an aggregate is written as the SQL of the aggregate
+func (c AggregateConfig) having(dialect Dialect, having []Predicate) []Predicate {
+	rewritten := make([]Predicate, len(having))
+	for i, predicate := range having {
+		rewritten[i] = predicate
+		if predicate.isGroup() {
+			rewritten[i].Predicates = c.having(dialect, predicate.Predicates)
+			continue
+		}
+		for _, aggregate := range c.Aggregates {
+			if aggregate.alias() == predicate.Field {
+				rewritten[i].expression = aggregate.toString(dialect)
+				break
+			}
+		}
+	}
+	return rewritten
+}
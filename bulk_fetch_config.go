@@ -1,6 +1,13 @@
 package surf
 
 import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -10,24 +17,310 @@ type BulkFetchConfig struct {
 	Offset     int
 	OrderBys   []OrderBy
 	Predicates []Predicate
+
+	// Fields are the names of the only fields that are selected, which
+	// defaults to all of the fields of the model
+	Fields []string
+
+	// Keyset pages through the rows with a Cursor rather than the Offset,
+	// which is ignored.  The OrderBys are followed by the first
+	// UniqueIdentifier field as a tiebreaker, so every row has a
+	// distinct position.
+	//
+	// The fields that are ordered by must not be NULL.
+	Keyset bool
+
+	// Cursor is a token returned by NextCursor, which fetches the rows that
+	// come after the model the cursor was built from.  Setting a Cursor
+	// implies Keyset.
+	Cursor string
+}
+
+// selects returns if the field of name is selected by the BulkFetchConfig
+func (c BulkFetchConfig) selects(name string) bool {
+	if len(c.Fields) == 0 {
+		return true
+	}
+	for _, field := range c.Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
 }
 
 // ConsumeSortQuery consumes a `sort` query parameter
 // and stuffs them into the OrderBys field
+//
+// Each column may end in `:nulls_first` or `:nulls_last`, such as
+// `sort=-second_owner:nulls_last`.  Empty columns, such as those of
+// `sort=` or `sort=name,`, are skipped.
 func (c *BulkFetchConfig) ConsumeSortQuery(sortQuery string) {
 	var orderBys []OrderBy
 	for _, sort := range strings.Split(sortQuery, ",") {
+		sort = strings.TrimSpace(sort)
+		if sort == "" || sort == "-" {
+			continue
+		}
+		orderBy := OrderBy{Field: sort, Type: ORDER_BY_ASC}
 		if string(sort[0]) == "-" {
-			orderBys = append(orderBys, OrderBy{
-				Field: sort[1:],
-				Type:  ORDER_BY_DESC,
-			})
-		} else {
-			orderBys = append(orderBys, OrderBy{
-				Field: sort,
-				Type:  ORDER_BY_ASC,
-			})
+			orderBy.Field = sort[1:]
+			orderBy.Type = ORDER_BY_DESC
 		}
+		if strings.HasSuffix(orderBy.Field, ":nulls_first") {
+			orderBy.Field = strings.TrimSuffix(orderBy.Field, ":nulls_first")
+			orderBy.Nulls = NULLS_FIRST
+		} else if strings.HasSuffix(orderBy.Field, ":nulls_last") {
+			orderBy.Field = strings.TrimSuffix(orderBy.Field, ":nulls_last")
+			orderBy.Nulls = NULLS_LAST
+		}
+		orderBys = append(orderBys, orderBy)
 	}
 	c.OrderBys = orderBys
 }
+
+// NewBulkFetchConfig returns the BulkFetchConfig of the `limit`, `offset`,
+// `sort`, `fields` and `filter` query parameters of query, for a BulkFetch
+// of model.
+//
+// The limit defaults to the DefaultLimit of the Configuration of model, and
+// is capped at its MaxLimit.  Only the Sortable fields of model may be sorted
+// by, along with its OrderExpressions, and only its Filterable fields may
+// be filtered by.
+func NewBulkFetchConfig(query url.Values, model Model) (BulkFetchConfig, error) {
+	config := model.GetConfiguration()
+	var fetchConfig BulkFetchConfig
+
+	// Limit + offset
+	fetchConfig.Limit = config.DefaultLimit
+	if fetchConfig.Limit <= 0 {
+		fetchConfig.Limit = 20
+	}
+	maxLimit := config.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = 100
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		fetchConfig.Limit, err = strconv.Atoi(limit)
+		if err != nil || fetchConfig.Limit < 1 {
+			return BulkFetchConfig{}, fmt.Errorf("The limit '%v' is not a positive integer", limit)
+		}
+	}
+	if fetchConfig.Limit > maxLimit {
+		fetchConfig.Limit = maxLimit
+	}
+	if offset := query.Get("offset"); offset != "" {
+		var err error
+		fetchConfig.Offset, err = strconv.Atoi(offset)
+		if err != nil || fetchConfig.Offset < 0 {
+			return BulkFetchConfig{}, fmt.Errorf("The offset '%v' is not a non-negative integer", offset)
+		}
+	}
+
+	// Sort
+	fetchConfig.ConsumeSortQuery(query.Get("sort"))
+	for _, orderBy := range fetchConfig.OrderBys {
+		_, sortable := config.OrderExpressions[orderBy.Field]
+		for _, field := range config.Fields {
+			if field.Name == orderBy.Field && field.Sortable {
+				sortable = true
+				break
+			}
+		}
+		if !sortable {
+			return BulkFetchConfig{}, invalidColumn("order", config, orderBy.Field)
+		}
+	}
+
+	// Fields
+	for _, name := range strings.Split(query.Get("fields"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			fetchConfig.Fields = append(fetchConfig.Fields, name)
+		}
+	}
+	_, err := config.projection(fetchConfig.Fields)
+	if err != nil {
+		return BulkFetchConfig{}, err
+	}
+
+	// Filters
+	err = fetchConfig.ConsumeFilterQuery(query, model)
+	if err != nil {
+		return BulkFetchConfig{}, err
+	}
+	return fetchConfig, nil
+}
+
+// filterQueryKey matches the `filter[field]` and `filter[field][operator]`
+// keys of a query
+var filterQueryKey = regexp.MustCompile(`^filter\[([^\]]+)\](?:\[([^\]]+)\])?$`)
+
+// filterOperators maps the operators of a `filter` query parameter
+// to their PredicateType
+var filterOperators = map[string]PredicateType{
+	"eq":       WHERE_EQUAL,
+	"ne":       WHERE_NOT_EQUAL,
+	"gt":       WHERE_GREATER_THAN,
+	"gte":      WHERE_GREATER_THAN_OR_EQUAL_TO,
+	"lt":       WHERE_LESS_THAN,
+	"lte":      WHERE_LESS_THAN_OR_EQUAL_TO,
+	"like":     WHERE_LIKE,
+	"not_like": WHERE_NOT_LIKE,
+	"ilike":    WHERE_ILIKE,
+	"in":       WHERE_IN,
+	"not_in":   WHERE_NOT_IN,
+	"between":  WHERE_BETWEEN,
+	"null":     WHERE_IS_NULL,
+}
+
+// ConsumeFilterQuery consumes the `filter[field][operator]=value` query
+// parameters of query and appends them to the Predicates field.  The
+// operator defaults to `eq` when it is left out.
+//
+// Only the Filterable fields of model may be filtered by, with the operators
+// that the Dialect of model has, and each value is converted to the Go type
+// of its field.  The values of `in`, `not_in` and `between` are comma
+// separated, and `null` takes `true` or `false`.
+func (c *BulkFetchConfig) ConsumeFilterQuery(query url.Values, model Model) error {
+	config := model.GetConfiguration()
+
+	// Sort the keys, so the predicates are in a stable order
+	var keys []string
+	for key := range query {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var predicates []Predicate
+	for _, key := range keys {
+		// Parse the key
+		match := filterQueryKey.FindStringSubmatch(key)
+		if match == nil {
+			return fmt.Errorf("The filter '%v' is not in the format filter[field][operator]", key)
+		}
+		name, operator := match[1], match[2]
+		if operator == "" {
+			operator = "eq"
+		}
+		var field *Field
+		for i := range config.Fields {
+			if config.Fields[i].Name == name && config.Fields[i].Filterable {
+				field = &config.Fields[i]
+				break
+			}
+		}
+		if field == nil {
+			return invalidColumn("filter", config, name)
+		}
+		predicateType, ok := filterOperators[operator]
+		if dialect, isSql := modelDialect(model); ok && isSql {
+			ok = dialect.SupportsPredicate(predicateType)
+		}
+		if !ok {
+			return &InvalidOperatorError{TableName: config.TableName, Column: name, Operator: operator}
+		}
+
+		// Build a predicate of each value
+		for _, value := range query[key] {
+			predicate, err := filterPredicate(config, field, predicateType, value)
+			if err != nil {
+				return err
+			}
+			predicates = append(predicates, predicate)
+		}
+	}
+	c.Predicates = append(c.Predicates, predicates...)
+	return nil
+}
+
+// filterPredicate returns the predicate of a single `filter` query parameter
+func filterPredicate(config *Configuration, field *Field, predicateType PredicateType, value string) (Predicate, error) {
+	predicate := Predicate{Field: field.Name, PredicateType: predicateType}
+	switch predicateType {
+	case WHERE_IS_NULL:
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return predicate, fmt.Errorf("Could not filter table '%v' by the invalid value '%v' of column '%v'",
+				config.TableName, value, field.Name)
+		}
+		if !isNull {
+			predicate.PredicateType = WHERE_IS_NOT_NULL
+		}
+		return predicate, nil
+	case WHERE_IN, WHERE_NOT_IN, WHERE_BETWEEN:
+		values := strings.Split(value, ",")
+		if predicateType == WHERE_BETWEEN && len(values) != 2 {
+			return predicate, fmt.Errorf("The filter 'between' of column '%v' requires two comma separated values",
+				field.Name)
+		}
+		for _, value := range values {
+			fieldValue, err := parseFieldValue(config, field, value)
+			if err != nil {
+				return predicate, err
+			}
+			predicate.Values = append(predicate.Values, fieldValue)
+		}
+		return predicate, nil
+	}
+
+	fieldValue, err := parseFieldValue(config, field, value)
+	if err != nil {
+		return predicate, err
+	}
+	predicate.Values = []interface{}{fieldValue}
+	return predicate, nil
+}
+
+// parseFieldValue converts value to the Go type of field
+//
+// Types that implement encoding.TextUnmarshaler, such as `time.Time` and
+// the `null` types, are converted with UnmarshalText.
+func parseFieldValue(config *Configuration, field *Field, value string) (interface{}, error) {
+	invalid := fmt.Errorf("Could not filter table '%v' by the invalid value '%v' of column '%v'",
+		config.TableName, value, field.Name)
+
+	fieldValue := reflect.New(reflect.TypeOf(field.Pointer).Elem())
+	if unmarshaler, ok := fieldValue.Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			return nil, invalid
+		}
+		return fieldValue.Elem().Interface(), nil
+	}
+
+	elem := fieldValue.Elem()
+	switch elem.Kind() {
+	case reflect.String:
+		elem.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, invalid
+		}
+		elem.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, elem.Type().Bits())
+		if err != nil {
+			return nil, invalid
+		}
+		elem.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, elem.Type().Bits())
+		if err != nil {
+			return nil, invalid
+		}
+		elem.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, elem.Type().Bits())
+		if err != nil {
+			return nil, invalid
+		}
+		elem.SetFloat(f)
+	default:
+		return nil, fmt.Errorf("Could not filter table '%v' by the column '%v' of type `%v`",
+			config.TableName, field.Name, elem.Type())
+	}
+	return elem.Interface(), nil
+}
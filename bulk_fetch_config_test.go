@@ -0,0 +1,162 @@
+package surf_test
+
+import (
+	"errors"
+	"github.com/go-carrot/surf"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+	"net/url"
+	"testing"
+)
+
+func TestConsumeSortQuery(t *testing.T) {
+	// Empty sorts are skipped
+	config := surf.BulkFetchConfig{}
+	config.ConsumeSortQuery("")
+	assert.Equal(t, 0, len(config.OrderBys))
+	config.ConsumeSortQuery("-age,,name,-")
+	assert.Equal(t, []surf.OrderBy{{Field: "age", Type: surf.ORDER_BY_DESC}, {Field: "name", Type: surf.ORDER_BY_ASC}}, config.OrderBys)
+
+	// Placement of NULLs
+	config = surf.BulkFetchConfig{}
+	config.ConsumeSortQuery("-second_owner:nulls_first,name:nulls_last")
+	assert.Equal(t, []surf.OrderBy{
+		{Field: "second_owner", Type: surf.ORDER_BY_DESC, Nulls: surf.NULLS_FIRST},
+		{Field: "name", Type: surf.ORDER_BY_ASC, Nulls: surf.NULLS_LAST},
+	}, config.OrderBys)
+}
+
+func TestNewBulkFetchConfig(t *testing.T) {
+	models := memoryModels(&surf.MemoryStore{})
+
+	// Defaults
+	config, err := surf.NewBulkFetchConfig(url.Values{}, NewAnimalWith(models))
+	assert.Nil(t, err)
+	assert.Equal(t, 20, config.Limit)
+	assert.Equal(t, 0, config.Offset)
+	config, err = surf.NewBulkFetchConfig(url.Values{"limit": {"1000"}}, NewAnimalWith(models))
+	assert.Nil(t, err)
+	assert.Equal(t, 100, config.Limit)
+
+	// Limits of the model
+	animal := NewAnimalWith(models)
+	animal.GetConfiguration().DefaultLimit = 5
+	animal.GetConfiguration().MaxLimit = 10
+	config, err = surf.NewBulkFetchConfig(url.Values{}, animal)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, config.Limit)
+	config, err = surf.NewBulkFetchConfig(url.Values{"limit": {"11"}}, animal)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, config.Limit)
+
+	// A full query
+	query, _ := url.ParseQuery("limit=2&offset=1&sort=-age&filter[name][like]=R%25&filter[age][gte]=2")
+	config, err = surf.NewBulkFetchConfig(query, NewAnimalWith(models))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, config.Limit)
+	assert.Equal(t, 1, config.Offset)
+	assert.Equal(t, []surf.OrderBy{{Field: "age", Type: surf.ORDER_BY_DESC}}, config.OrderBys)
+	assert.Equal(t, []surf.Predicate{
+		{Field: "age", PredicateType: surf.WHERE_GREATER_THAN_OR_EQUAL_TO, Values: []interface{}{2}},
+		{Field: "name", PredicateType: surf.WHERE_LIKE, Values: []interface{}{"R%"}},
+	}, config.Predicates)
+
+	// Invalid queries
+	for _, rawQuery := range []string{
+		"limit=abc",
+		"limit=0",
+		"offset=-1",
+		"sort=helloworld",
+		"filter[slug]=bob",
+	} {
+		query, _ := url.ParseQuery(rawQuery)
+		_, err := surf.NewBulkFetchConfig(query, NewAnimalWith(models))
+		assert.NotNil(t, err, rawQuery)
+	}
+	var invalidColumnErr *surf.InvalidColumnError
+	_, err = surf.NewBulkFetchConfig(url.Values{"sort": {"-slug"}}, NewAnimalWith(models))
+	assert.True(t, errors.As(err, &invalidColumnErr))
+	assert.Equal(t, "order", invalidColumnErr.Action)
+}
+
+func TestConsumeFilterQuery(t *testing.T) {
+	models := memoryModels(&surf.MemoryStore{})
+
+	// filterPredicates returns the predicates of the filters of query
+	filterPredicates := func(model surf.Model, query string) []surf.Predicate {
+		values, err := url.ParseQuery(query)
+		assert.Nil(t, err)
+		config := surf.BulkFetchConfig{}
+		err = config.ConsumeFilterQuery(values, model)
+		assert.Nil(t, err, query)
+		return config.Predicates
+	}
+
+	assert.Equal(t, []surf.Predicate{
+		{Field: "name", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{"Luna"}},
+	}, filterPredicates(NewAnimalWith(models), "filter[name]=Luna"))
+	assert.Equal(t, []surf.Predicate{
+		{Field: "age", PredicateType: surf.WHERE_IN, Values: []interface{}{2, 5}},
+	}, filterPredicates(NewAnimalWith(models), "filter[age][in]=2,5"))
+	assert.Equal(t, []surf.Predicate{
+		{Field: "age", PredicateType: surf.WHERE_BETWEEN, Values: []interface{}{3, 4}},
+	}, filterPredicates(NewAnimalWith(models), "filter[age][between]=3,4"))
+	assert.Equal(t, []surf.Predicate{
+		{Field: "age", PredicateType: surf.WHERE_NOT_EQUAL, Values: []interface{}{4}},
+		{Field: "age", PredicateType: surf.WHERE_NOT_EQUAL, Values: []interface{}{5}},
+	}, filterPredicates(NewAnimalWith(models), "filter[age][ne]=4&filter[age][ne]=5"))
+	assert.Nil(t, filterPredicates(NewAnimalWith(models), "sort=-age"))
+
+	// Filter by a null.Int
+	assert.Equal(t, []surf.Predicate{
+		{Field: "second_owner", PredicateType: surf.WHERE_IS_NULL},
+	}, filterPredicates(NewToyWith(models), "filter[second_owner][null]=true"))
+	assert.Equal(t, []surf.Predicate{
+		{Field: "second_owner", PredicateType: surf.WHERE_IS_NOT_NULL},
+	}, filterPredicates(NewToyWith(models), "filter[second_owner][null]=false"))
+	assert.Equal(t, []surf.Predicate{
+		{Field: "second_owner", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{null.IntFrom(2)}},
+	}, filterPredicates(NewToyWith(models), "filter[second_owner]=2"))
+
+	// Invalid filters
+	for _, query := range []string{
+		"filter[slug]=bob",
+		"filter[helloworld]=1",
+		"filter[age][gte]=three",
+		"filter[age][near]=3",
+		"filter[age][between]=3",
+		"filter[age][null]=maybe",
+		"filter[age",
+	} {
+		values, _ := url.ParseQuery(query)
+		config := surf.BulkFetchConfig{}
+		err := config.ConsumeFilterQuery(values, NewAnimalWith(models))
+		assert.NotNil(t, err, query)
+		assert.Nil(t, config.Predicates)
+	}
+	var invalidColumnErr *surf.InvalidColumnError
+	config := surf.BulkFetchConfig{}
+	err := config.ConsumeFilterQuery(url.Values{"filter[slug]": {"bob"}}, NewAnimalWith(models))
+	assert.True(t, errors.As(err, &invalidColumnErr))
+
+	// Operators are validated against the Dialect of the model
+	var invalidOperatorErr *surf.InvalidOperatorError
+	ilike := url.Values{"filter[name][ilike]": {"r%"}}
+	for _, test := range []struct {
+		models modelBuilder
+		ok     bool
+	}{
+		{models, true},
+		{pqModels(nil), true},
+		{sqlModels(surf.SqliteDialect{})(nil), false},
+		{mysqlModels(nil), false},
+	} {
+		config := surf.BulkFetchConfig{}
+		err := config.ConsumeFilterQuery(ilike, NewAnimalWith(test.models))
+		assert.Equal(t, test.ok, err == nil)
+		if !test.ok {
+			assert.True(t, errors.As(err, &invalidOperatorErr))
+			assert.Equal(t, "ilike", invalidOperatorErr.Operator)
+		}
+	}
+}
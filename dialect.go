@@ -0,0 +1,425 @@
+package surf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ReturningType is an enumeration of the ways a database can hand
+// back the rows that an INSERT or UPDATE has written
+type ReturningType int
+
+const (
+	RETURNING_NONE   ReturningType = iota // The row must be re-selected
+	RETURNING_CLAUSE                      // A trailing `RETURNING col, ...`
+	RETURNING_OUTPUT                      // An `OUTPUT INSERTED.col, ...` before VALUES / WHERE
+)
+
+// Dialect is the definition of how a SqlModel spells its queries
+// for a specific database
+type Dialect interface {
+	// Placeholder returns the bind parameter for the value at valueIndex,
+	// which starts at 1
+	Placeholder(valueIndex int) string
+
+	// QuoteIdentifier returns a table or column name as it should
+	// be written into a query.  Each part of a qualified name, such
+	// as `schema.table`, is quoted on its own.
+	QuoteIdentifier(identifier string) string
+
+	// Returning returns how rows written by an INSERT or UPDATE are read back
+	Returning() ReturningType
+
+	// LimitOffset returns the clause that pages a SELECT.  ordered specifies
+	// if the SELECT already has an ORDER BY clause.
+	LimitOffset(limit int, offset int, ordered bool) string
+
+	// InsertBatchSize returns the most rows that a single multi-row INSERT
+	// may hold, where each row has valuesPerRow bind parameters
+	InsertBatchSize(valuesPerRow int) int
+
+	// RowValues returns if the database can compare row values,
+	// such as `(a, b) > (1, 2)`
+	RowValues() bool
+
+	// NullsOrdering returns if the database can order by
+	// `NULLS FIRST` and `NULLS LAST`
+	NullsOrdering() bool
+
+	// SupportsPredicate returns if the database has the operator
+	// of predicateType
+	SupportsPredicate(predicateType PredicateType) bool
+
+	// Upsert returns the clause that is appended to an INSERT to resolve a
+	// conflict on conflictFields by updating updateFields, or by doing
+	// nothing in the event there are no updateFields.  A clause that updates
+	// must end with its assignments, so that more may be appended to it.
+	Upsert(conflictFields []string, updateFields []string) (string, error)
+
+	// Savepoint returns the statement that creates the savepoint name
+	Savepoint(name string) string
+
+	// ReleaseSavepoint returns the statement that releases the savepoint
+	// name, or an empty string if the database has no such statement
+	ReleaseSavepoint(name string) string
+
+	// RollbackToSavepoint returns the statement that rolls back to
+	// the savepoint name
+	RollbackToSavepoint(name string) string
+}
+
+// PostgresDialect is the Dialect of PostgreSQL
+type PostgresDialect struct{}
+
+// Placeholder returns a `$N` bind parameter
+func (d PostgresDialect) Placeholder(valueIndex int) string {
+	return "$" + strconv.Itoa(valueIndex)
+}
+
+// QuoteIdentifier wraps the identifier in double quotes
+func (d PostgresDialect) QuoteIdentifier(identifier string) string {
+	return quoteIdentifier(identifier, "\"", "\"")
+}
+
+// Returning returns RETURNING_CLAUSE
+func (d PostgresDialect) Returning() ReturningType {
+	return RETURNING_CLAUSE
+}
+
+// LimitOffset returns a `LIMIT n OFFSET m` clause
+func (d PostgresDialect) LimitOffset(limit int, offset int, ordered bool) string {
+	return limitOffset(limit, offset)
+}
+
+// InsertBatchSize returns the rows that fit in 65535 bind parameters
+func (d PostgresDialect) InsertBatchSize(valuesPerRow int) int {
+	return insertBatchSize(65535, valuesPerRow)
+}
+
+// RowValues returns true
+func (d PostgresDialect) RowValues() bool {
+	return true
+}
+
+// NullsOrdering returns true
+func (d PostgresDialect) NullsOrdering() bool {
+	return true
+}
+
+// SupportsPredicate returns true
+func (d PostgresDialect) SupportsPredicate(predicateType PredicateType) bool {
+	return true
+}
+
+// Upsert returns an `ON CONFLICT` clause
+func (d PostgresDialect) Upsert(conflictFields []string, updateFields []string) (string, error) {
+	return onConflict(d, conflictFields, updateFields)
+}
+
+// Savepoint returns a `SAVEPOINT` statement
+func (d PostgresDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + name + ";"
+}
+
+// ReleaseSavepoint returns a `RELEASE SAVEPOINT` statement
+func (d PostgresDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + name + ";"
+}
+
+// RollbackToSavepoint returns a `ROLLBACK TO SAVEPOINT` statement
+func (d PostgresDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name + ";"
+}
+
+// MySQLDialect is the Dialect of MySQL
+type MySQLDialect struct{}
+
+// Placeholder returns a `?` bind parameter
+func (d MySQLDialect) Placeholder(valueIndex int) string {
+	return "?"
+}
+
+// QuoteIdentifier wraps the identifier in backticks
+func (d MySQLDialect) QuoteIdentifier(identifier string) string {
+	return quoteIdentifier(identifier, "`", "`")
+}
+
+// Returning returns RETURNING_NONE
+func (d MySQLDialect) Returning() ReturningType {
+	return RETURNING_NONE
+}
+
+// LimitOffset returns a `LIMIT n OFFSET m` clause
+func (d MySQLDialect) LimitOffset(limit int, offset int, ordered bool) string {
+	return limitOffset(limit, offset)
+}
+
+// InsertBatchSize returns the rows that fit in 65535 bind parameters
+func (d MySQLDialect) InsertBatchSize(valuesPerRow int) int {
+	return insertBatchSize(65535, valuesPerRow)
+}
+
+// RowValues returns true
+func (d MySQLDialect) RowValues() bool {
+	return true
+}
+
+// NullsOrdering returns false
+func (d MySQLDialect) NullsOrdering() bool {
+	return false
+}
+
+// SupportsPredicate returns false for `IS DISTINCT FROM`, and the
+// operators that only Postgres has
+func (d MySQLDialect) SupportsPredicate(predicateType PredicateType) bool {
+	return predicateType != WHERE_IS_DISTINCT_FROM && !postgresOnlyPredicate(predicateType)
+}
+
+// Upsert returns an `ON DUPLICATE KEY UPDATE` clause.
+//
+// MySQL resolves conflicts on any unique key, so conflictFields is only used
+// to write a no-op update when there are no updateFields.
+func (d MySQLDialect) Upsert(conflictFields []string, updateFields []string) (string, error) {
+	if len(updateFields) == 0 {
+		if len(conflictFields) == 0 {
+			return "", errors.New("An upsert that does nothing requires at least one conflict field")
+		}
+		field := d.QuoteIdentifier(conflictFields[0])
+		return "ON DUPLICATE KEY UPDATE " + field + "=" + field, nil
+	}
+
+	clause := "ON DUPLICATE KEY UPDATE "
+	for i, field := range updateFields {
+		clause += d.QuoteIdentifier(field) + "=VALUES(" + d.QuoteIdentifier(field) + ")"
+		if (i + 1) < len(updateFields) {
+			clause += ", "
+		}
+	}
+	return clause, nil
+}
+
+// Savepoint returns a `SAVEPOINT` statement
+func (d MySQLDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + name + ";"
+}
+
+// ReleaseSavepoint returns a `RELEASE SAVEPOINT` statement
+func (d MySQLDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + name + ";"
+}
+
+// RollbackToSavepoint returns a `ROLLBACK TO SAVEPOINT` statement
+func (d MySQLDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name + ";"
+}
+
+// SqliteDialect is the Dialect of SQLite 3.35 or later
+type SqliteDialect struct{}
+
+// Placeholder returns a `?NNN` bind parameter
+func (d SqliteDialect) Placeholder(valueIndex int) string {
+	return "?" + strconv.Itoa(valueIndex)
+}
+
+// QuoteIdentifier wraps the identifier in double quotes
+func (d SqliteDialect) QuoteIdentifier(identifier string) string {
+	return quoteIdentifier(identifier, "\"", "\"")
+}
+
+// Returning returns RETURNING_CLAUSE
+func (d SqliteDialect) Returning() ReturningType {
+	return RETURNING_CLAUSE
+}
+
+// LimitOffset returns a `LIMIT n OFFSET m` clause
+func (d SqliteDialect) LimitOffset(limit int, offset int, ordered bool) string {
+	return limitOffset(limit, offset)
+}
+
+// InsertBatchSize returns the rows that fit in 32766 bind parameters,
+// which is the default limit of SQLite 3.32 or later
+func (d SqliteDialect) InsertBatchSize(valuesPerRow int) int {
+	return insertBatchSize(32766, valuesPerRow)
+}
+
+// RowValues returns true
+func (d SqliteDialect) RowValues() bool {
+	return true
+}
+
+// NullsOrdering returns true
+func (d SqliteDialect) NullsOrdering() bool {
+	return true
+}
+
+// SupportsPredicate returns false for the operators that only Postgres has
+func (d SqliteDialect) SupportsPredicate(predicateType PredicateType) bool {
+	return !postgresOnlyPredicate(predicateType)
+}
+
+// Upsert returns an `ON CONFLICT` clause
+func (d SqliteDialect) Upsert(conflictFields []string, updateFields []string) (string, error) {
+	return onConflict(d, conflictFields, updateFields)
+}
+
+// Savepoint returns a `SAVEPOINT` statement
+func (d SqliteDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + name + ";"
+}
+
+// ReleaseSavepoint returns a `RELEASE SAVEPOINT` statement
+func (d SqliteDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + name + ";"
+}
+
+// RollbackToSavepoint returns a `ROLLBACK TO SAVEPOINT` statement
+func (d SqliteDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name + ";"
+}
+
+// MssqlDialect is the Dialect of Microsoft SQL Server 2012 or later
+type MssqlDialect struct{}
+
+// Placeholder returns a `@pN` bind parameter
+func (d MssqlDialect) Placeholder(valueIndex int) string {
+	return "@p" + strconv.Itoa(valueIndex)
+}
+
+// QuoteIdentifier wraps the identifier in square brackets
+func (d MssqlDialect) QuoteIdentifier(identifier string) string {
+	return quoteIdentifier(identifier, "[", "]")
+}
+
+// Returning returns RETURNING_OUTPUT
+func (d MssqlDialect) Returning() ReturningType {
+	return RETURNING_OUTPUT
+}
+
+// LimitOffset returns an `OFFSET m ROWS FETCH NEXT n ROWS ONLY` clause.
+//
+// SQL Server only allows this clause after an ORDER BY, so a no-op
+// ORDER BY is written first if the SELECT isn't ordered.
+func (d MssqlDialect) LimitOffset(limit int, offset int, ordered bool) string {
+	clause := ""
+	if !ordered {
+		clause += " ORDER BY (SELECT NULL)"
+	}
+	clause += " OFFSET " + strconv.Itoa(offset) + " ROWS FETCH NEXT " + strconv.Itoa(limit) + " ROWS ONLY"
+	return clause
+}
+
+// InsertBatchSize returns the rows that fit in 2100 bind parameters,
+// up to the 1000 rows that SQL Server allows in a VALUES clause
+func (d MssqlDialect) InsertBatchSize(valuesPerRow int) int {
+	batchSize := insertBatchSize(2100, valuesPerRow)
+	if batchSize > 1000 {
+		return 1000
+	}
+	return batchSize
+}
+
+// RowValues returns false
+func (d MssqlDialect) RowValues() bool {
+	return false
+}
+
+// NullsOrdering returns false
+func (d MssqlDialect) NullsOrdering() bool {
+	return false
+}
+
+// SupportsPredicate returns false for `IS DISTINCT FROM`, which SQL Server
+// only has as of 2022, and the operators that only Postgres has
+func (d MssqlDialect) SupportsPredicate(predicateType PredicateType) bool {
+	return predicateType != WHERE_IS_DISTINCT_FROM && !postgresOnlyPredicate(predicateType)
+}
+
+// Upsert returns an error, as SQL Server can only upsert through MERGE
+func (d MssqlDialect) Upsert(conflictFields []string, updateFields []string) (string, error) {
+	return "", errors.New("MssqlDialect does not support upserts")
+}
+
+// Savepoint returns a `SAVE TRANSACTION` statement
+func (d MssqlDialect) Savepoint(name string) string {
+	return "SAVE TRANSACTION " + name + ";"
+}
+
+// ReleaseSavepoint returns an empty string, as SQL Server releases its
+// savepoints when the transaction ends
+func (d MssqlDialect) ReleaseSavepoint(name string) string {
+	return ""
+}
+
+// RollbackToSavepoint returns a `ROLLBACK TRANSACTION` statement
+func (d MssqlDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TRANSACTION " + name + ";"
+}
+
+// postgresOnlyPredicate returns if predicateType is an operator
+// that only Postgres has
+func postgresOnlyPredicate(predicateType PredicateType) bool {
+	switch predicateType {
+	case WHERE_ILIKE,
+		WHERE_REGEX,
+		WHERE_IREGEX,
+		WHERE_ANY,
+		WHERE_CONTAINS,
+		WHERE_CONTAINED_BY,
+		WHERE_OVERLAPS,
+		WHERE_HAS_KEY,
+		WHERE_JSON_TEXT_EQUAL,
+		WHERE_MATCHES:
+		return true
+	}
+	return false
+}
+
+// limitOffset returns the `LIMIT n OFFSET m` clause shared by most dialects
+func limitOffset(limit int, offset int) string {
+	return " LIMIT " + strconv.Itoa(limit) + " OFFSET " + strconv.Itoa(offset)
+}
+
+// quoteIdentifier wraps each dot separated part of identifier in the open
+// and close quotes, where any close quote within a part is doubled
+func quoteIdentifier(identifier string, open string, close string) string {
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		parts[i] = open + strings.Replace(part, close, close+close, -1) + close
+	}
+	return strings.Join(parts, ".")
+}
+
+// insertBatchSize returns the number of rows of valuesPerRow bind
+// parameters that fit within maxParameters, which is at least 1
+func insertBatchSize(maxParameters int, valuesPerRow int) int {
+	if valuesPerRow < 1 || valuesPerRow > maxParameters {
+		return 1
+	}
+	return maxParameters / valuesPerRow
+}
+
+// onConflict returns the `ON CONFLICT` clause shared by Postgres and SQLite
+func onConflict(dialect Dialect, conflictFields []string, updateFields []string) (string, error) {
+	if len(conflictFields) == 0 {
+		return "", errors.New("An upsert requires at least one conflict field")
+	}
+
+	quotedConflictFields := make([]string, len(conflictFields))
+	for i, field := range conflictFields {
+		quotedConflictFields[i] = dialect.QuoteIdentifier(field)
+	}
+	clause := "ON CONFLICT (" + strings.Join(quotedConflictFields, ", ") + ") DO "
+	if len(updateFields) == 0 {
+		return clause + "NOTHING", nil
+	}
+
+	clause += "UPDATE SET "
+	for i, field := range updateFields {
+		clause += dialect.QuoteIdentifier(field) + "=EXCLUDED." + dialect.QuoteIdentifier(field)
+		if (i + 1) < len(updateFields) {
+			clause += ", "
+		}
+	}
+	return clause, nil
+}
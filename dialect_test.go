@@ -0,0 +1,104 @@
+package surf_test
+
+import (
+	"github.com/go-carrot/surf"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPostgresDialect(t *testing.T) {
+	dialect := surf.PostgresDialect{}
+	assert.Equal(t, "$3", dialect.Placeholder(3))
+	assert.Equal(t, `"animals"`, dialect.QuoteIdentifier("animals"))
+	assert.Equal(t, `"public"."Animals"`, dialect.QuoteIdentifier("public.Animals"))
+	assert.Equal(t, `"a""b"`, dialect.QuoteIdentifier(`a"b`))
+	assert.Equal(t, surf.RETURNING_CLAUSE, dialect.Returning())
+	assert.Equal(t, " LIMIT 10 OFFSET 20", dialect.LimitOffset(10, 20, false))
+	assert.Equal(t, 21845, dialect.InsertBatchSize(3))
+	assert.Equal(t, 1, dialect.InsertBatchSize(70000))
+	assert.True(t, dialect.RowValues())
+	assert.True(t, dialect.NullsOrdering())
+	assert.True(t, dialect.SupportsPredicate(surf.WHERE_ILIKE))
+	assert.True(t, dialect.SupportsPredicate(surf.WHERE_IS_DISTINCT_FROM))
+
+	upsert, err := dialect.Upsert([]string{"slug"}, []string{"name", "age"})
+	assert.Nil(t, err)
+	assert.Equal(t, `ON CONFLICT ("slug") DO UPDATE SET "name"=EXCLUDED."name", "age"=EXCLUDED."age"`, upsert)
+
+	upsert, err = dialect.Upsert([]string{"slug"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `ON CONFLICT ("slug") DO NOTHING`, upsert)
+
+	_, err = dialect.Upsert(nil, []string{"name"})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "SAVEPOINT sp;", dialect.Savepoint("sp"))
+	assert.Equal(t, "RELEASE SAVEPOINT sp;", dialect.ReleaseSavepoint("sp"))
+	assert.Equal(t, "ROLLBACK TO SAVEPOINT sp;", dialect.RollbackToSavepoint("sp"))
+}
+
+func TestMySQLDialect(t *testing.T) {
+	dialect := surf.MySQLDialect{}
+	assert.Equal(t, "?", dialect.Placeholder(3))
+	assert.Equal(t, "`animals`", dialect.QuoteIdentifier("animals"))
+	assert.Equal(t, "`a``b`", dialect.QuoteIdentifier("a`b"))
+	assert.Equal(t, surf.RETURNING_NONE, dialect.Returning())
+	assert.Equal(t, " LIMIT 10 OFFSET 20", dialect.LimitOffset(10, 20, true))
+	assert.Equal(t, 21845, dialect.InsertBatchSize(3))
+	assert.True(t, dialect.RowValues())
+	assert.False(t, dialect.NullsOrdering())
+	assert.True(t, dialect.SupportsPredicate(surf.WHERE_LIKE))
+	assert.False(t, dialect.SupportsPredicate(surf.WHERE_ILIKE))
+	assert.False(t, dialect.SupportsPredicate(surf.WHERE_IS_DISTINCT_FROM))
+
+	upsert, err := dialect.Upsert([]string{"slug"}, []string{"name", "age"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE `name`=VALUES(`name`), `age`=VALUES(`age`)", upsert)
+
+	upsert, err = dialect.Upsert([]string{"slug"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE `slug`=`slug`", upsert)
+
+	_, err = dialect.Upsert(nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestSqliteDialect(t *testing.T) {
+	dialect := surf.SqliteDialect{}
+	assert.Equal(t, "?3", dialect.Placeholder(3))
+	assert.Equal(t, `"animals"`, dialect.QuoteIdentifier("animals"))
+	assert.Equal(t, surf.RETURNING_CLAUSE, dialect.Returning())
+	assert.Equal(t, " LIMIT 10 OFFSET 20", dialect.LimitOffset(10, 20, false))
+	assert.Equal(t, 10922, dialect.InsertBatchSize(3))
+	assert.True(t, dialect.RowValues())
+	assert.True(t, dialect.NullsOrdering())
+	assert.False(t, dialect.SupportsPredicate(surf.WHERE_REGEX))
+	assert.True(t, dialect.SupportsPredicate(surf.WHERE_IS_DISTINCT_FROM))
+
+	upsert, err := dialect.Upsert([]string{"slug"}, []string{"name"})
+	assert.Nil(t, err)
+	assert.Equal(t, `ON CONFLICT ("slug") DO UPDATE SET "name"=EXCLUDED."name"`, upsert)
+}
+
+func TestMssqlDialect(t *testing.T) {
+	dialect := surf.MssqlDialect{}
+	assert.Equal(t, "@p3", dialect.Placeholder(3))
+	assert.Equal(t, "[animals]", dialect.QuoteIdentifier("animals"))
+	assert.Equal(t, "[dbo].[a]]b]", dialect.QuoteIdentifier("dbo.a]b"))
+	assert.Equal(t, surf.RETURNING_OUTPUT, dialect.Returning())
+	assert.Equal(t, " OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY", dialect.LimitOffset(10, 20, true))
+	assert.Equal(t, " ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY", dialect.LimitOffset(10, 20, false))
+	assert.Equal(t, 700, dialect.InsertBatchSize(3))
+	assert.Equal(t, 1000, dialect.InsertBatchSize(1))
+	assert.False(t, dialect.RowValues())
+	assert.False(t, dialect.NullsOrdering())
+	assert.False(t, dialect.SupportsPredicate(surf.WHERE_ILIKE))
+	assert.False(t, dialect.SupportsPredicate(surf.WHERE_IS_DISTINCT_FROM))
+
+	_, err := dialect.Upsert([]string{"slug"}, []string{"name"})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "SAVE TRANSACTION sp;", dialect.Savepoint("sp"))
+	assert.Equal(t, "", dialect.ReleaseSavepoint("sp"))
+	assert.Equal(t, "ROLLBACK TRANSACTION sp;", dialect.RollbackToSavepoint("sp"))
+}
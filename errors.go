@@ -0,0 +1,58 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStaleObject is returned by an Update or Delete of a model with a Version
+// field, in the event that no row has the version of the model, as the row
+// was changed or deleted after the model was read
+var ErrStaleObject = errors.New("The row has been changed or deleted since the model was read")
+
+// InvalidColumnError is returned in the event that a query refers to a
+// column that isn't the Name of one of the Fields of a Configuration
+type InvalidColumnError struct {
+	TableName string
+	Column    string
+
+	// Action is what the query tried to do with the column, which is one of
+	// `select`, `filter`, `order`, `group`, `aggregate`, `update` or `upsert`
+	Action string
+}
+
+// Error returns the message of the error
+func (e *InvalidColumnError) Error() string {
+	if e.Action == "select" {
+		return fmt.Sprintf("Could not select the invalid column '%v' of table '%v'", e.Column, e.TableName)
+	}
+	preposition := "by"
+	switch e.Action {
+	case "update":
+		preposition = "with"
+	case "upsert":
+		preposition = "on"
+	}
+	return fmt.Sprintf("Could not %v table '%v' %v the invalid column '%v'",
+		e.Action, e.TableName, preposition, e.Column)
+}
+
+// invalidColumn returns an InvalidColumnError of column of the table of config
+func invalidColumn(action string, config *Configuration, column string) error {
+	return &InvalidColumnError{TableName: config.TableName, Column: column, Action: action}
+}
+
+// InvalidOperatorError is returned in the event that a filter or a Predicate
+// refers to an operator that is unknown, or that the database of the model
+// doesn't have
+type InvalidOperatorError struct {
+	TableName string
+	Column    string
+	Operator  string
+}
+
+// Error returns the message of the error
+func (e *InvalidOperatorError) Error() string {
+	return fmt.Sprintf("Could not filter table '%v' by the invalid operator '%v' of column '%v'",
+		e.TableName, e.Operator, e.Column)
+}
@@ -0,0 +1,271 @@
+package surf
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// keysetCursor is the decoded contents of a BulkFetchConfig.Cursor
+type keysetCursor struct {
+	// OrderBys are the keyset OrderBys the cursor was built with, in the
+	// same format as a `sort` query parameter
+	OrderBys []string          `json:"o"`
+	Values   []json.RawMessage `json:"v"`
+}
+
+// keyset returns if the BulkFetchConfig pages with a Cursor
+func (c BulkFetchConfig) keyset() bool {
+	return c.Keyset || c.Cursor != ""
+}
+
+// keysetOrderBys returns the OrderBys of the BulkFetchConfig, followed by
+// the first UniqueIdentifier field of config as a tiebreaker, unless the
+// OrderBys already contain a UniqueIdentifier field.
+//
+// The tiebreaker is ordered in the same direction as the last OrderBy, so
+// that the seek can be written as a single row value comparison.  A field
+// that may be NULL, and that is ordered by NULLS_DEFAULT, is ordered with
+// its NULL values placed as Postgres places them, so that every database
+// pages through them in the same order.
+func (c BulkFetchConfig) keysetOrderBys(config *Configuration) ([]OrderBy, error) {
+	orderBys := append([]OrderBy{}, c.OrderBys...)
+	for i, orderBy := range orderBys {
+		if orderBy.Rank != nil {
+			return nil, errors.New("Keyset pagination cannot order by the rank of a text search")
+		}
+		if _, ok := config.OrderExpressions[orderBy.Field]; ok && !containsField(config.Fields, orderBy.Field) {
+			return nil, fmt.Errorf("Keyset pagination cannot order by the expression '%v'", orderBy.Field)
+		}
+		if !c.selects(orderBy.Field) {
+			return nil, fmt.Errorf("Keyset pagination requires the field '%v' that is ordered by to be selected",
+				orderBy.Field)
+		}
+		if orderBy.Nulls == NULLS_DEFAULT && nullableField(config, orderBy.Field) {
+			orderBys[i].Nulls = NULLS_LAST
+			if orderBy.Type == ORDER_BY_DESC {
+				orderBys[i].Nulls = NULLS_FIRST
+			}
+		}
+	}
+	var tiebreaker *Field
+	for i, field := range config.Fields {
+		if !field.UniqueIdentifier {
+			continue
+		}
+		for _, orderBy := range orderBys {
+			if orderBy.Field == field.Name {
+				return orderBys, nil
+			}
+		}
+		if tiebreaker == nil {
+			tiebreaker = &config.Fields[i]
+		}
+	}
+	if tiebreaker == nil {
+		return nil, fmt.Errorf("Keyset pagination of table '%v' requires a UniqueIdentifier field",
+			config.TableName)
+	}
+
+	if !c.selects(tiebreaker.Name) {
+		return nil, fmt.Errorf("Keyset pagination requires the field '%v' that is ordered by to be selected",
+			tiebreaker.Name)
+	}
+	orderByType := ORDER_BY_ASC
+	if len(orderBys) > 0 {
+		orderByType = orderBys[len(orderBys)-1].Type
+	}
+	return append(orderBys, OrderBy{Field: tiebreaker.Name, Type: orderByType}), nil
+}
+
+// seek returns the keyset OrderBys of the BulkFetchConfig, along with the
+// values of its Cursor in the types of the fields of config.  The values
+// are nil in the event there is no Cursor.
+func (c BulkFetchConfig) seek(config *Configuration) ([]OrderBy, []interface{}, error) {
+	orderBys, err := c.keysetOrderBys(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Cursor == "" {
+		return orderBys, nil, nil
+	}
+
+	// Decode the cursor
+	invalid := errors.New("The cursor is invalid, or was built with different OrderBys")
+	data, err := base64.RawURLEncoding.DecodeString(c.Cursor)
+	if err != nil {
+		return nil, nil, invalid
+	}
+	var cursor keysetCursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || len(cursor.OrderBys) != len(orderBys) || len(cursor.Values) != len(orderBys) {
+		return nil, nil, invalid
+	}
+
+	// Convert each value to the type of its field
+	values := make([]interface{}, len(orderBys))
+	for i, orderBy := range orderBys {
+		if cursor.OrderBys[i] != orderBy.sortString() {
+			return nil, nil, invalid
+		}
+		for _, field := range config.Fields {
+			if field.Name == orderBy.Field {
+				value := reflect.New(reflect.TypeOf(field.Pointer).Elem())
+				err := json.Unmarshal(cursor.Values[i], value.Interface())
+				if err != nil {
+					return nil, nil, invalid
+				}
+				values[i] = value.Elem().Interface()
+				break
+			}
+		}
+		if values[i] == nil {
+			return nil, nil, invalid
+		}
+	}
+	return orderBys, values, nil
+}
+
+// NextCursor returns the Cursor that fetches the rows that come after model,
+// which is typically the last model returned by a BulkFetch
+// with this BulkFetchConfig
+func (c BulkFetchConfig) NextCursor(model Model) (string, error) {
+	config := model.GetConfiguration()
+	orderBys, err := c.keysetOrderBys(config)
+	if err != nil {
+		return "", err
+	}
+
+	var cursor keysetCursor
+	for _, orderBy := range orderBys {
+		found := false
+		for _, field := range config.Fields {
+			if field.Name == orderBy.Field {
+				value, err := json.Marshal(field.Pointer)
+				if err != nil {
+					return "", err
+				}
+				cursor.OrderBys = append(cursor.OrderBys, orderBy.sortString())
+				cursor.Values = append(cursor.Values, value)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", invalidColumn("order", config, orderBy.Field)
+		}
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// seekToString converts the keyset orderBys and the values of a cursor to a
+// predicate that matches the rows that come after the cursor, along with its
+// values to be passed along with the query
+//
+// A single row value comparison is only written when none of the orderBys
+// place their NULL values, as a comparison with NULL matches no rows.
+func seekToString(dialect Dialect, valueIndex int, orderBys []OrderBy, cursorValues []interface{}) (string, []interface{}) {
+	values := make([]interface{}, 0)
+
+	// (a, b) > ($1, $2)
+	rowValues := dialect.RowValues()
+	for i, orderBy := range orderBys {
+		rowValues = rowValues && orderBy.Type == orderBys[0].Type &&
+			orderBy.Nulls == NULLS_DEFAULT && !nullValue(cursorValues[i])
+	}
+	if rowValues {
+		columns, placeholders := "", ""
+		for i, orderBy := range orderBys {
+			if i > 0 {
+				columns += ", "
+				placeholders += ", "
+			}
+			columns += dialect.QuoteIdentifier(orderBy.Field)
+			placeholders += dialect.Placeholder(valueIndex)
+			values = append(values, cursorValues[i])
+			valueIndex++
+		}
+		return "(" + columns + ") " + orderBys[0].seekOperator() + " (" + placeholders + ")", values
+	}
+
+	// ((a > $1 OR a IS NULL) OR (a = $2 AND b < $3))
+	var clauses []string
+	for i, orderBy := range orderBys {
+		// Nothing comes after a NULL that is placed last, other than
+		// the NULL values that are equal to it
+		null := nullValue(cursorValues[i])
+		if null && orderBy.Nulls != NULLS_FIRST {
+			continue
+		}
+
+		var comparisons []string
+		for j := 0; j < i; j++ {
+			column := dialect.QuoteIdentifier(orderBys[j].Field)
+			if nullValue(cursorValues[j]) {
+				comparisons = append(comparisons, column+" IS NULL")
+				continue
+			}
+			comparisons = append(comparisons, column+" = "+dialect.Placeholder(valueIndex))
+			values = append(values, cursorValues[j])
+			valueIndex++
+		}
+
+		column := dialect.QuoteIdentifier(orderBy.Field)
+		switch {
+		case null:
+			comparisons = append(comparisons, column+" IS NOT NULL")
+		case orderBy.Nulls == NULLS_LAST:
+			comparisons = append(comparisons, "("+column+" "+orderBy.seekOperator()+" "+dialect.Placeholder(valueIndex)+" OR "+column+" IS NULL)")
+			values = append(values, cursorValues[i])
+			valueIndex++
+		default:
+			comparisons = append(comparisons, column+" "+orderBy.seekOperator()+" "+dialect.Placeholder(valueIndex))
+			values = append(values, cursorValues[i])
+			valueIndex++
+		}
+		clauses = append(clauses, "("+strings.Join(comparisons, " AND ")+")")
+	}
+	if len(clauses) == 0 {
+		return "(1 = 0)", values
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", values
+}
+
+// nullableField returns if the field of name of config may hold NULL, which
+// is a field of a pointer, or of a struct with a Valid flag such as `null.Int`
+func nullableField(config *Configuration, name string) bool {
+	for _, field := range config.Fields {
+		if field.Name != name {
+			continue
+		}
+		fieldType := reflect.TypeOf(field.Pointer).Elem()
+		switch fieldType.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			return true
+		case reflect.Struct:
+			_, ok := fieldType.FieldByName("Valid")
+			return ok
+		}
+		return false
+	}
+	return false
+}
+
+// nullValue returns if value is written to the database as NULL
+func nullValue(value interface{}) bool {
+	if valuer, ok := value.(driver.Valuer); ok {
+		driverValue, err := valuer.Value()
+		return err == nil && driverValue == nil
+	}
+	rv := reflect.ValueOf(value)
+	return !rv.IsValid() || (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil()
+}
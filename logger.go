@@ -1,6 +1,7 @@
 package surf
 
 import (
+	"bytes"
 	"fmt"
 	"gopkg.in/guregu/null.v3"
 	"io"
@@ -33,6 +34,26 @@ func PrintSqlQuery(query string, args ...interface{}) {
 	}
 }
 
+// printQuery prints a query whose bind parameters are written in the
+// style of dialect if the user has enabled logging
+func printQuery(dialect Dialect, query string, args ...interface{}) {
+	if loggingEnabled {
+		var queryBuffer bytes.Buffer
+		for i, arg := range args {
+			placeholder := dialect.Placeholder(i + 1)
+			index := strings.Index(query, placeholder)
+			if index == -1 {
+				break
+			}
+			queryBuffer.WriteString(query[:index])
+			queryBuffer.WriteString(pointerToLogString(arg))
+			query = query[index+len(placeholder):]
+		}
+		queryBuffer.WriteString(query)
+		fmt.Fprint(loggingWriter, queryBuffer.String())
+	}
+}
+
 // pointerToLogString converts a value pointer to the string
 // that should be logged for it
 func pointerToLogString(pointer interface{}) string {
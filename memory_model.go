@@ -0,0 +1,1662 @@
+package surf
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/guregu/null.v3"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore holds the rows of every MemoryModel that shares it, keyed
+// by the TableName of each model's Configuration.
+//
+// The zero value is an empty store that is ready to use, and a store may
+// be shared by any number of goroutines.
+type MemoryStore struct {
+	mutex  sync.RWMutex
+	tables map[string]*memoryTable
+}
+
+// memoryTable is a single table of a MemoryStore
+type memoryTable struct {
+	rows   []memoryRow
+	lastId int64
+}
+
+// memoryRow is a single row of a memoryTable, keyed by Field.Name
+type memoryRow map[string]interface{}
+
+// read calls fn with the table named tableName while holding a read lock
+func (s *MemoryStore) read(tableName string, fn func(*memoryTable) error) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	table, ok := s.tables[tableName]
+	if !ok {
+		table = &memoryTable{}
+	}
+	return fn(table)
+}
+
+// write calls fn with the table named tableName while holding a write lock
+func (s *MemoryStore) write(tableName string, fn func(*memoryTable) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.tables == nil {
+		s.tables = make(map[string]*memoryTable)
+	}
+	table, ok := s.tables[tableName]
+	if !ok {
+		table = &memoryTable{}
+		s.tables[tableName] = table
+	}
+	return fn(table)
+}
+
+// MemoryModel is an in process implementation of a Model, which is
+// intended to stand in for a database backed Model in unit tests
+type MemoryModel struct {
+	Store  *MemoryStore  `json:"-"`
+	Config Configuration `json:"-"`
+}
+
+// MemoryModel implements every optional interface of a Model
+var _ fullModel = (*MemoryModel)(nil)
+
+// GetConfiguration returns the configuration for the model
+func (w *MemoryModel) GetConfiguration() *Configuration {
+	return &w.Config
+}
+
+// Insert inserts the model into the store
+func (w *MemoryModel) Insert() error {
+	return w.InsertContext(context.Background())
+}
+
+// InsertContext inserts the model into the store
+//
+// The first UniqueIdentifier that is not Insertable is treated as an auto
+// incrementing integer if it is an `int64`, `int` or `null.Int`.
+func (w *MemoryModel) InsertContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		return table.insert(&w.Config)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeign(ctx, w)
+}
+
+// BulkInsert inserts an array of models into the store
+func (w *MemoryModel) BulkInsert(models []Model) error {
+	return w.BulkInsertContext(context.Background(), models)
+}
+
+// BulkInsertContext inserts an array of models into the store
+//
+// The models are inserted as they are by InsertContext, but in the event
+// any model can't be inserted, none of the models are inserted.
+func (w *MemoryModel) BulkInsertContext(ctx context.Context, models []Model) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		rows, lastId := table.rows, table.lastId
+		for _, model := range models {
+			err := table.insert(model.GetConfiguration())
+			if err != nil {
+				table.rows, table.lastId = rows, lastId
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeigns(ctx, func() Model { return w }, models)
+}
+
+// Upsert inserts the model into the store, or resolves a
+// conflict with an existing row
+func (w *MemoryModel) Upsert(upsertConfig UpsertConfig) error {
+	return w.UpsertContext(context.Background(), upsertConfig)
+}
+
+// UpsertContext inserts the model into the store, or resolves a
+// conflict with an existing row
+//
+// An existing row is any row that shares the values of all of the
+// conflict fields with the model.
+func (w *MemoryModel) UpsertContext(ctx context.Context, upsertConfig UpsertConfig) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Get Conflict Fields
+	conflictFields, err := upsertConfig.conflictFields(&w.Config)
+	if err != nil {
+		return err
+	}
+
+	// Insert or update the row
+	err = w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		i := table.find(conflictFields...)
+		if i == -1 {
+			return table.insert(&w.Config)
+		}
+
+		// Copy the row, so a conflict leaves it untouched
+		row := make(memoryRow)
+		for name, value := range table.rows[i] {
+			row[name] = value
+		}
+		if upsertConfig.Action == UPSERT_DO_UPDATE {
+			updated := false
+			for _, field := range w.Config.Fields {
+				if field.Insertable && field.Updatable && !containsField(conflictFields, field.Name) {
+					row[field.Name] = memoryFieldValue(field)
+					updated = true
+				}
+			}
+			if version, ok := versionField(&w.Config); ok && updated && !(version.Insertable && version.Updatable) {
+				row[version.Name] = memoryNextVersion(version, row[version.Name])
+			}
+		}
+
+		err := table.checkUnique(w.Config, row, i)
+		if err != nil {
+			return err
+		}
+		table.rows[i] = row
+		return memoryScanRow(&w.Config, w.Config.Fields, row)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeign(ctx, w)
+}
+
+// Load loads the model from the store from its unique identifier
+// and then loads those values into the struct
+func (w *MemoryModel) Load() error {
+	return w.LoadContext(context.Background())
+}
+
+// LoadContext loads the model from the store from its unique identifier
+// and then loads those values into the struct
+func (w *MemoryModel) LoadContext(ctx context.Context) error {
+	return w.LoadFieldsContext(ctx, nil)
+}
+
+// LoadFields loads the fields of names of the model from the store
+// from its unique identifier, leaving its other fields untouched
+func (w *MemoryModel) LoadFields(names []string) error {
+	return w.LoadFieldsContext(context.Background(), names)
+}
+
+// LoadFieldsContext loads the fields of names of the model from the store
+// from its unique identifier, leaving its other fields untouched
+func (w *MemoryModel) LoadFieldsContext(ctx context.Context, names []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Get Unique Identifier + fields
+	uniqueIdentifierField, err := getUniqueIdentifier(w)
+	if err != nil {
+		return err
+	}
+	fields, err := w.Config.projection(names)
+	if err != nil {
+		return err
+	}
+
+	// Find the row, unless it is soft deleted
+	predicates := w.Config.scopeDeleted(ctx, nil)
+	err = w.Store.read(w.Config.TableName, func(table *memoryTable) error {
+		i := table.find(uniqueIdentifierField)
+		if i == -1 || !memoryMatchesAll(predicates, table.rows[i]) {
+			return sql.ErrNoRows
+		}
+		return memoryScanRow(&w.Config, fields, table.rows[i])
+	})
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeign(ctx, w)
+}
+
+// Update updates the model with the current values in the struct
+func (w *MemoryModel) Update() error {
+	return w.UpdateContext(context.Background())
+}
+
+// UpdateContext updates the model with the current values in the struct
+//
+// Only the Updatable fields that have changed since the model was last read
+// are set, and the update is skipped in the event that none have changed.
+// In the event the model has a Version field, ErrStaleObject is returned
+// unless the row has the version of the model, which is then incremented.
+func (w *MemoryModel) UpdateContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Get Unique Identifier + Version
+	uniqueIdentifierField, err := getUniqueIdentifier(w)
+	if err != nil {
+		return err
+	}
+	version, versioned := versionField(&w.Config)
+
+	// Get changed updatable fields
+	var updatableFields []Field
+	for _, field := range w.Config.Fields {
+		if field.Updatable && !field.Version && w.Config.isChanged(field) {
+			updatableFields = append(updatableFields, field)
+		}
+	}
+
+	// If there's nothing to update, exit early
+	if len(updatableFields) == 0 {
+		return nil
+	}
+
+	// Update the row
+	err = w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		i := table.find(uniqueIdentifierField)
+		if versioned && (i == -1 || !table.hasVersion(i, version)) {
+			return ErrStaleObject
+		}
+		if i == -1 {
+			return sql.ErrNoRows
+		}
+
+		// Copy the row, so a conflict leaves it untouched
+		row := make(memoryRow)
+		for name, value := range table.rows[i] {
+			row[name] = value
+		}
+		for _, field := range updatableFields {
+			row[field.Name] = memoryFieldValue(field)
+		}
+		if versioned {
+			row[version.Name] = nextVersion(version)
+		}
+
+		err := table.checkUnique(w.Config, row, i)
+		if err != nil {
+			return err
+		}
+		table.rows[i] = row
+		return memoryScanRow(&w.Config, w.Config.Fields, row)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeign(ctx, w)
+}
+
+// BulkUpdate sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *MemoryModel) BulkUpdate(predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.BulkUpdateContext(context.Background(), predicates, values)
+}
+
+// BulkUpdateContext sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+//
+// In the event the model has a Version field that isn't in values, the
+// version of each row is incremented.  In the event any row can't be
+// updated, none of the rows are updated.
+func (w *MemoryModel) BulkUpdateContext(ctx context.Context, predicates []Predicate, values map[string]interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// Validate predicates + values
+	if len(predicates) == 0 {
+		return 0, errors.New("BulkUpdate requires at least one predicate")
+	}
+	err := validatePredicates(&w.Config, predicates)
+	if err != nil {
+		return 0, err
+	}
+	updateFields, err := bulkUpdateFields(&w.Config, values)
+	if err != nil {
+		return 0, err
+	}
+	updateValues := make(memoryRow)
+	for _, field := range updateFields {
+		updateValues[field.Name], err = memoryConvert(field, values[field.Name])
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	predicates = w.Config.scopeDeleted(ctx, predicates)
+	version, versioned := versionField(&w.Config)
+	incrementVersion := versioned && !containsField(updateFields, version.Name)
+
+	// Update the rows
+	var count int64
+	err = w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		rows := make([]memoryRow, len(table.rows))
+		copy(rows, table.rows)
+		for i, existingRow := range table.rows {
+			if !memoryMatchesAll(predicates, existingRow) {
+				continue
+			}
+
+			// Copy the row, so a conflict leaves it untouched
+			row := make(memoryRow)
+			for name, value := range existingRow {
+				row[name] = value
+			}
+			for name, value := range updateValues {
+				row[name] = value
+			}
+			if incrementVersion {
+				row[version.Name] = memoryNextVersion(version, row[version.Name])
+			}
+
+			err := table.checkUnique(w.Config, row, i)
+			if err != nil {
+				table.rows = rows
+				return err
+			}
+			table.rows[i] = row
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Delete deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *MemoryModel) Delete() error {
+	return w.DeleteContext(context.Background())
+}
+
+// DeleteContext deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *MemoryModel) DeleteContext(ctx context.Context) error {
+	if _, ok := w.Config.deletedAtField(); ok {
+		deletedAt := time.Now().UTC()
+		return w.setDeletedAt(ctx, &deletedAt)
+	}
+	return w.HardDeleteContext(ctx)
+}
+
+// HardDelete deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *MemoryModel) HardDelete() error {
+	return w.HardDeleteContext(context.Background())
+}
+
+// HardDeleteContext deletes the row of the model, even in the event
+// that it has a DeletedAtField
+//
+// In the event the model has a Version field, ErrStaleObject is returned
+// unless the row has the version of the model.
+func (w *MemoryModel) HardDeleteContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Get Unique Identifier + Version
+	uniqueIdentifierField, err := getUniqueIdentifier(w)
+	if err != nil {
+		return err
+	}
+	version, versioned := versionField(&w.Config)
+
+	// Delete the row
+	return w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		i := table.find(uniqueIdentifierField)
+		if versioned && (i == -1 || !table.hasVersion(i, version)) {
+			return ErrStaleObject
+		}
+		if i == -1 {
+			return errors.New("Nothing was deleted")
+		}
+		table.rows = append(table.rows[:i], table.rows[i+1:]...)
+		return nil
+	})
+}
+
+// Restore undoes the soft delete of the model
+func (w *MemoryModel) Restore() error {
+	return w.RestoreContext(context.Background())
+}
+
+// RestoreContext undoes the soft delete of the model
+func (w *MemoryModel) RestoreContext(ctx context.Context) error {
+	if _, ok := w.Config.deletedAtField(); !ok {
+		return fmt.Errorf("Could not restore a model of table '%v', as it has no DeletedAtField",
+			w.Config.TableName)
+	}
+	return w.setDeletedAt(ctx, nil)
+}
+
+// setDeletedAt soft deletes the model at deletedAt, or restores it in the
+// event deletedAt is nil
+//
+// In the event the model has a Version field, ErrStaleObject is returned
+// unless the row has the version of the model, which is then incremented.
+func (w *MemoryModel) setDeletedAt(ctx context.Context, deletedAt *time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Get Unique Identifier + Version
+	uniqueIdentifierField, err := getUniqueIdentifier(w)
+	if err != nil {
+		return err
+	}
+	version, versioned := versionField(&w.Config)
+
+	// Get Set Fields
+	deletedAtField, _ := w.Config.deletedAtField()
+	setFields := []Field{deletedAtField}
+	setValues := []interface{}{deletedAtValue(deletedAtField, deletedAt)}
+	if versioned {
+		setFields = append(setFields, version)
+		setValues = append(setValues, nextVersion(version))
+	}
+
+	// Update the row
+	err = w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		i := table.find(uniqueIdentifierField)
+		if i == -1 || !memoryMatches(w.Config.deletedPredicate(deletedAt == nil), table.rows[i]) ||
+			versioned && !table.hasVersion(i, version) {
+			if versioned {
+				return ErrStaleObject
+			}
+			if deletedAt == nil {
+				return errors.New("Nothing was restored")
+			}
+			return errors.New("Nothing was deleted")
+		}
+
+		// Copy the row, so that it isn't shared with a previous read
+		row := make(memoryRow)
+		for name, value := range table.rows[i] {
+			row[name] = value
+		}
+		for j, field := range setFields {
+			row[field.Name] = setValues[j]
+		}
+		table.rows[i] = row
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Set the values on the model
+	for i, field := range setFields {
+		setFieldValue(field, setValues[i])
+	}
+	w.Config.refreshSnapshot(setFields)
+	return nil
+}
+
+// BulkDelete deletes all rows that match predicates, or soft deletes them
+// in the event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *MemoryModel) BulkDelete(predicates []Predicate) (int64, error) {
+	return w.BulkDeleteContext(context.Background(), predicates)
+}
+
+// BulkDeleteContext deletes all rows that match predicates, or soft deletes
+// them in the event that it has a DeletedAtField, returning the number of
+// rows that were deleted
+func (w *MemoryModel) BulkDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	deletedAtField, ok := w.Config.deletedAtField()
+	if !ok {
+		return w.BulkHardDeleteContext(ctx, predicates)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// Validate predicates
+	if len(predicates) == 0 {
+		return 0, errors.New("BulkDelete requires at least one predicate")
+	}
+	err := validatePredicates(&w.Config, predicates)
+	if err != nil {
+		return 0, err
+	}
+	predicates = w.Config.scopeUndeleted(ctx, predicates)
+	deletedAt := time.Now().UTC()
+	version, versioned := versionField(&w.Config)
+
+	// Soft delete the rows
+	var count int64
+	err = w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		for i, existingRow := range table.rows {
+			if !memoryMatchesAll(predicates, existingRow) {
+				continue
+			}
+
+			// Copy the row, so that it isn't shared with a previous read
+			row := make(memoryRow)
+			for name, value := range existingRow {
+				row[name] = value
+			}
+			row[deletedAtField.Name] = deletedAtValue(deletedAtField, &deletedAt)
+			if versioned {
+				row[version.Name] = memoryNextVersion(version, row[version.Name])
+			}
+			table.rows[i] = row
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// BulkHardDelete deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *MemoryModel) BulkHardDelete(predicates []Predicate) (int64, error) {
+	return w.BulkHardDeleteContext(context.Background(), predicates)
+}
+
+// BulkHardDeleteContext deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows that
+// were deleted
+func (w *MemoryModel) BulkHardDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// Validate predicates
+	if len(predicates) == 0 {
+		return 0, errors.New("BulkDelete requires at least one predicate")
+	}
+	err := validatePredicates(&w.Config, predicates)
+	if err != nil {
+		return 0, err
+	}
+	predicates = w.Config.scopeDeleted(ctx, predicates)
+
+	// Delete the rows
+	var count int64
+	err = w.Store.write(w.Config.TableName, func(table *memoryTable) error {
+		var rows []memoryRow
+		for _, row := range table.rows {
+			if memoryMatchesAll(predicates, row) {
+				count++
+			} else {
+				rows = append(rows, row)
+			}
+		}
+		table.rows = rows
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Count returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *MemoryModel) Count(fetchConfig BulkFetchConfig) (int64, error) {
+	return w.CountContext(context.Background(), fetchConfig)
+}
+
+// CountContext returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *MemoryModel) CountContext(ctx context.Context, fetchConfig BulkFetchConfig) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	fetchConfig.Predicates = w.Config.scopeDeleted(ctx, fetchConfig.Predicates)
+
+	// Validate predicates
+	err := validatePredicates(&w.Config, fetchConfig.Predicates)
+	if err != nil {
+		return 0, err
+	}
+
+	// Count the rows
+	var count int64
+	err = w.Store.read(w.Config.TableName, func(table *memoryTable) error {
+		for _, row := range table.rows {
+			if memoryMatchesAll(fetchConfig.Predicates, row) {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Exists returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *MemoryModel) Exists(fetchConfig BulkFetchConfig) (bool, error) {
+	return w.ExistsContext(context.Background(), fetchConfig)
+}
+
+// ExistsContext returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *MemoryModel) ExistsContext(ctx context.Context, fetchConfig BulkFetchConfig) (bool, error) {
+	count, err := w.CountContext(ctx, fetchConfig)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Aggregate runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *MemoryModel) Aggregate(aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.AggregateContext(context.Background(), aggregateConfig)
+}
+
+// AggregateContext runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *MemoryModel) AggregateContext(ctx context.Context, aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	aggregateConfig.Predicates = w.Config.scopeDeleted(ctx, aggregateConfig.Predicates)
+
+	// Validate
+	err := aggregateConfig.validate(&w.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the rows, in the order that each group is first seen
+	var keys []string
+	groups := make(map[string][]memoryRow)
+	err = w.Store.read(w.Config.TableName, func(table *memoryTable) error {
+		for _, row := range table.rows {
+			if !memoryMatchesAll(aggregateConfig.Predicates, row) {
+				continue
+			}
+			key := memoryGroupKey(aggregateConfig.GroupBys, row)
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// As in SQL, aggregating without a GROUP BY always returns one row
+	if len(aggregateConfig.GroupBys) == 0 && len(keys) == 0 {
+		keys = append(keys, "")
+	}
+
+	// Aggregate each group
+	results := make([]AggregateRow, 0)
+	for _, key := range keys {
+		rows := groups[key]
+		result := AggregateRow{Groups: make(map[string]interface{}), Values: make(map[string]interface{})}
+		havingRow := make(memoryRow)
+		for _, groupBy := range aggregateConfig.GroupBys {
+			value := rows[0][groupBy]
+			if memoryNormalize(value) == nil {
+				value = nil
+			}
+			result.Groups[groupBy] = value
+			havingRow[groupBy] = value
+		}
+		for _, aggregate := range aggregateConfig.Aggregates {
+			value, err := memoryAggregate(aggregate, rows)
+			if err != nil {
+				return nil, err
+			}
+			result.Values[aggregate.alias()] = value
+			havingRow[aggregate.alias()] = value
+		}
+		if memoryMatchesAll(aggregateConfig.Having, havingRow) {
+			results = append(results, result)
+		}
+	}
+
+	// Order the groups
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, orderBy := range aggregateConfig.OrderBys {
+			comparison := memoryOrderBy(orderBy, results[i].value(orderBy.Field), results[j].value(orderBy.Field))
+			if comparison != 0 {
+				return comparison < 0
+			}
+		}
+		return false
+	})
+
+	// Apply the offset + limit
+	if aggregateConfig.Offset > len(results) {
+		results = results[:0]
+	} else if aggregateConfig.Offset > 0 {
+		results = results[aggregateConfig.Offset:]
+	}
+	if aggregateConfig.Limit > 0 && aggregateConfig.Limit < len(results) {
+		results = results[:aggregateConfig.Limit]
+	}
+	return results, nil
+}
+
+// BulkFetch gets an array of models
+func (w *MemoryModel) BulkFetch(fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	return w.BulkFetchContext(context.Background(), fetchConfig, buildModel)
+}
+
+// BulkFetchContext gets an array of models
+func (w *MemoryModel) BulkFetchContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	// Stuff into []Model
+	models := make([]Model, 0)
+	err := w.StreamContext(ctx, fetchConfig, buildModel, StreamConfig{}, func(model Model) error {
+		models = append(models, model)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand foreign references
+	err = expandForeigns(ctx, buildModel, models)
+	if err != nil {
+		return nil, err
+	}
+
+	// OK
+	return models, nil
+}
+
+// Stream calls handle with each of the models of a bulk fetch
+func (w *MemoryModel) Stream(fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.StreamContext(context.Background(), fetchConfig, buildModel, streamConfig, handle)
+}
+
+// StreamContext calls handle with each of the models of a bulk fetch
+func (w *MemoryModel) StreamContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	foreignConfig := buildModel().GetConfiguration()
+	fetchConfig.Predicates = foreignConfig.scopeDeleted(ctx, fetchConfig.Predicates)
+
+	// Set up keyset pagination
+	orderBys, offset := fetchConfig.OrderBys, fetchConfig.Offset
+	var cursorValues []interface{}
+	if fetchConfig.keyset() {
+		var err error
+		orderBys, cursorValues, err = fetchConfig.seek(&w.Config)
+		if err != nil {
+			return err
+		}
+		offset = 0
+	}
+
+	// Validate fields + predicates + order bys
+	_, err := w.Config.projection(fetchConfig.Fields)
+	if err != nil {
+		return err
+	}
+	err = validatePredicates(foreignConfig, fetchConfig.Predicates)
+	if err != nil {
+		return err
+	}
+	for _, orderBy := range orderBys {
+		if _, ok := w.Config.OrderExpressions[orderBy.Field]; ok && !containsField(w.Config.Fields, orderBy.Field) {
+			return fmt.Errorf("Could not order table '%v' by the expression '%v', as the MemoryModel can't evaluate SQL",
+				w.Config.TableName, orderBy.Field)
+		}
+		if !containsField(w.Config.Fields, orderBy.Field) {
+			return invalidColumn("order", &w.Config, orderBy.Field)
+		}
+	}
+
+	// Select the rows
+	var rows []memoryRow
+	err = w.Store.read(foreignConfig.TableName, func(table *memoryTable) error {
+		for _, row := range table.rows {
+			if memoryMatchesAll(fetchConfig.Predicates, row) &&
+				(cursorValues == nil || memorySeek(orderBys, cursorValues, row)) {
+				rows = append(rows, row)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Order the rows
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, orderBy := range orderBys {
+			comparison := memoryOrderBy(orderBy, rows[i][orderBy.Field], rows[j][orderBy.Field])
+			if orderBy.Rank != nil {
+				comparison = memoryOrderBy(orderBy, memoryRank(*orderBy.Rank, rows[i]), memoryRank(*orderBy.Rank, rows[j]))
+			}
+			if comparison != 0 {
+				return comparison < 0
+			}
+		}
+		return false
+	})
+
+	// Apply the offset + limit
+	if offset > len(rows) {
+		rows = nil
+	} else if offset > 0 {
+		rows = rows[offset:]
+	}
+	if fetchConfig.Limit < len(rows) {
+		rows = rows[:fetchConfig.Limit]
+	}
+
+	// Stream each row into a model
+	batch := streamConfig.batch(ctx, buildModel, handle)
+	for _, row := range rows {
+		model := buildModel()
+		modelConfig := model.GetConfiguration()
+		fields, err := modelConfig.projection(fetchConfig.Fields)
+		if err != nil {
+			return err
+		}
+		err = memoryScanRow(modelConfig, fields, row)
+		if err != nil {
+			return err
+		}
+		err = batch.add(model)
+		if err != nil {
+			return err
+		}
+	}
+	return batch.flush()
+}
+
+// insert inserts the values of a model into the table
+//
+// The first UniqueIdentifier that is not Insertable is treated as an auto
+// incrementing integer if it is an `int64`, `int` or `null.Int`.  A Version
+// field that is not Insertable starts at 1, or the current time, as it would
+// by the default of its column.  All other fields that are not Insertable
+// are stored as their zero value.
+func (t *memoryTable) insert(config *Configuration) error {
+	// Build the row, where a Version field starts at its first version
+	row := make(memoryRow)
+	for _, field := range config.Fields {
+		if field.Insertable {
+			row[field.Name] = memoryFieldValue(field)
+		} else if field.Version {
+			row[field.Name] = memoryNextVersion(field, nil)
+		} else {
+			row[field.Name] = reflect.Zero(reflect.TypeOf(field.Pointer).Elem()).Interface()
+		}
+	}
+
+	// Set the auto incrementing field
+	for _, field := range config.Fields {
+		if field.UniqueIdentifier && !field.Insertable {
+			switch field.Pointer.(type) {
+			case *int64, *int, *null.Int:
+				t.lastId++
+				value := reflect.New(reflect.TypeOf(field.Pointer).Elem())
+				setInt64(value.Interface(), t.lastId)
+				row[field.Name] = value.Elem().Interface()
+			}
+			break
+		}
+	}
+
+	// Insert
+	err := t.checkUnique(*config, row, -1)
+	if err != nil {
+		return err
+	}
+	t.rows = append(t.rows, row)
+	return memoryScanRow(config, config.Fields, row)
+}
+
+// find returns the index of the first row that matches the values of
+// all of the fields, or -1 if there is no matching row
+func (t *memoryTable) find(fields ...Field) int {
+FindRow:
+	for i, row := range t.rows {
+		for _, field := range fields {
+			comparison, ok := memoryCompare(row[field.Name], memoryFieldValue(field))
+			if !ok || comparison != 0 {
+				continue FindRow
+			}
+		}
+		return i
+	}
+	return -1
+}
+
+// hasVersion returns if the row at index i has the value of the version field
+func (t *memoryTable) hasVersion(i int, version Field) bool {
+	comparison, ok := memoryCompare(t.rows[i][version.Name], memoryFieldValue(version))
+	return ok && comparison == 0
+}
+
+// checkUnique returns an error if any row other than the row at index skip
+// shares a value of a UniqueIdentifier field with row
+func (t *memoryTable) checkUnique(config Configuration, row memoryRow, skip int) error {
+	for _, field := range config.Fields {
+		if !field.UniqueIdentifier {
+			continue
+		}
+		for i, existingRow := range t.rows {
+			if i == skip {
+				continue
+			}
+			if comparison, ok := memoryCompare(existingRow[field.Name], row[field.Name]); ok && comparison == 0 {
+				return fmt.Errorf("Duplicate value for the unique column '%v' of table '%v'",
+					field.Name, config.TableName)
+			}
+		}
+	}
+	return nil
+}
+
+// memoryNextVersion returns the value that an update sets the Version field
+// of a row to, from the value of the field in the row
+func memoryNextVersion(field Field, value interface{}) interface{} {
+	pointer := reflect.New(reflect.TypeOf(field.Pointer).Elem())
+	if value != nil && reflect.TypeOf(value).AssignableTo(pointer.Elem().Type()) {
+		pointer.Elem().Set(reflect.ValueOf(value))
+	}
+	return nextVersion(Field{Name: field.Name, Pointer: pointer.Interface()})
+}
+
+// memoryFieldValue returns a copy of the value that a field points to
+func memoryFieldValue(field Field) interface{} {
+	return reflect.ValueOf(field.Pointer).Elem().Interface()
+}
+
+// memoryScanRow copies the values of a row into fields of config, in the same
+// way that a row is scanned into a Model by the SQL implementations, and
+// records the fields as loaded
+func memoryScanRow(config *Configuration, fields []Field, row memoryRow) error {
+	for _, field := range fields {
+		target := reflect.ValueOf(field.Pointer).Elem()
+		value, ok := row[field.Name]
+		if !ok || value == nil {
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+		if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+			return fmt.Errorf("Cannot scan a `%T` into the `%T` of column '%v'", value, field.Pointer, field.Name)
+		}
+		target.Set(reflect.ValueOf(value))
+	}
+	config.setLoaded(fields)
+	return nil
+}
+
+// memoryConvert converts a value to the type of the field, so that it can be
+// stored in a row.  Numbers may be converted to other numbers, and strings to
+// other strings.
+func memoryConvert(field Field, value interface{}) (interface{}, error) {
+	target := reflect.TypeOf(field.Pointer).Elem()
+	if value == nil {
+		return reflect.Zero(target).Interface(), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(target) {
+		return value, nil
+	}
+	if memoryIsNumber(rv.Kind()) && memoryIsNumber(target.Kind()) ||
+		rv.Kind() == reflect.String && target.Kind() == reflect.String {
+		return rv.Convert(target).Interface(), nil
+	}
+	return nil, fmt.Errorf("Cannot set a `%T` into the `%T` of column '%v'", value, field.Pointer, field.Name)
+}
+
+// memoryIsNumber returns if kind is an integer or float kind
+func memoryIsNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// memoryNormalize converts a value to the int64, float64, string, bool,
+// time.Time or nil that it represents, so that it can be compared
+func memoryNormalize(value interface{}) interface{} {
+	if valuer, ok := value.(driver.Valuer); ok {
+		driverValue, err := valuer.Value()
+		if err != nil {
+			return nil
+		}
+		value = driverValue
+	}
+
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		return memoryNormalize(rv.Elem().Interface())
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	if byteSlice, ok := value.([]byte); ok {
+		return string(byteSlice)
+	}
+	return value
+}
+
+// memoryCompare compares two values, returning -1, 0 or 1 if a is less than,
+// equal to or greater than b.  The second return value is false in the event
+// that either value is NULL, or the values are not comparable.
+func memoryCompare(a interface{}, b interface{}) (int, bool) {
+	a, b = memoryNormalize(a), memoryNormalize(b)
+	if a == nil || b == nil {
+		return 0, false
+	}
+
+	// Mixed numbers are compared as floats
+	if aInt, ok := a.(int64); ok {
+		if _, ok := b.(float64); ok {
+			a = float64(aInt)
+		}
+	}
+	if bInt, ok := b.(int64); ok {
+		if _, ok := a.(float64); ok {
+			b = float64(bInt)
+		}
+	}
+
+	switch av := a.(type) {
+	case int64:
+		if bv, ok := b.(int64); ok {
+			return compareOrdered(av < bv, av > bv), true
+		}
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return compareOrdered(av < bv, av > bv), true
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), true
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			return compareOrdered(!av && bv, av && !bv), true
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return compareOrdered(av.Before(bv), av.After(bv)), true
+		}
+	}
+	return 0, false
+}
+
+// compareOrdered converts the result of a less than and a greater
+// than comparison into -1, 0 or 1
+func compareOrdered(less bool, greater bool) int {
+	if less {
+		return -1
+	} else if greater {
+		return 1
+	}
+	return 0
+}
+
+// memoryOrder compares two values for an ORDER BY.  As in Postgres,
+// NULL values are ordered after all other values.
+func memoryOrder(a interface{}, b interface{}) int {
+	aNull, bNull := memoryNormalize(a) == nil, memoryNormalize(b) == nil
+	if aNull || bNull {
+		return compareOrdered(!aNull && bNull, aNull && !bNull)
+	}
+	comparison, _ := memoryCompare(a, b)
+	return comparison
+}
+
+// memoryOrderBy compares two values in the direction of orderBy, placing
+// NULL values as its NullsOrder specifies
+func memoryOrderBy(orderBy OrderBy, a interface{}, b interface{}) int {
+	aNull, bNull := memoryNormalize(a) == nil, memoryNormalize(b) == nil
+	if orderBy.Nulls != NULLS_DEFAULT && (aNull || bNull) {
+		comparison := compareOrdered(aNull && !bNull, !aNull && bNull)
+		if orderBy.Nulls == NULLS_LAST {
+			return -comparison
+		}
+		return comparison
+	}
+	comparison := memoryOrder(a, b)
+	if orderBy.Type == ORDER_BY_DESC {
+		return -comparison
+	}
+	return comparison
+}
+
+// memoryGroupKey returns a key that is shared by all rows that have
+// the same values of the groupBys fields
+func memoryGroupKey(groupBys []string, row memoryRow) string {
+	values := make([]interface{}, len(groupBys))
+	for i, groupBy := range groupBys {
+		values[i] = memoryNormalize(row[groupBy])
+	}
+	return fmt.Sprintf("%#v", values)
+}
+
+// memoryAggregate returns the value of an aggregate over rows, in the
+// same types as an AggregateRow that is read from a database
+func memoryAggregate(aggregate Aggregate, rows []memoryRow) (interface{}, error) {
+	if aggregate.Function == AGGREGATE_COUNT && aggregate.Field == "" {
+		return int64(len(rows)), nil
+	}
+
+	// As in SQL, NULL values are ignored
+	var values []interface{}
+	for _, row := range rows {
+		if memoryNormalize(row[aggregate.Field]) != nil {
+			values = append(values, row[aggregate.Field])
+		}
+	}
+
+	switch aggregate.Function {
+	case AGGREGATE_COUNT:
+		return int64(len(values)), nil
+	case AGGREGATE_COUNT_DISTINCT:
+		distinct := make(map[string]bool)
+		for _, value := range values {
+			distinct[fmt.Sprintf("%#v", memoryNormalize(value))] = true
+		}
+		return int64(len(distinct)), nil
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	switch aggregate.Function {
+	case AGGREGATE_SUM, AGGREGATE_AVG:
+		sum := 0.0
+		for _, value := range values {
+			switch nv := memoryNormalize(value).(type) {
+			case int64:
+				sum += float64(nv)
+			case float64:
+				sum += nv
+			default:
+				return nil, fmt.Errorf("Could not %v the non numeric column '%v'",
+					aggregate.functionName(), aggregate.Field)
+			}
+		}
+		if aggregate.Function == AGGREGATE_AVG {
+			return sum / float64(len(values)), nil
+		}
+		return sum, nil
+	}
+
+	// MIN + MAX
+	result := values[0]
+	for _, value := range values[1:] {
+		comparison, _ := memoryCompare(value, result)
+		if aggregate.Function == AGGREGATE_MIN && comparison < 0 ||
+			aggregate.Function == AGGREGATE_MAX && comparison > 0 {
+			result = value
+		}
+	}
+	return result, nil
+}
+
+// memorySeek returns if a row comes after the values of a cursor
+// in the order of orderBys
+func memorySeek(orderBys []OrderBy, cursorValues []interface{}, row memoryRow) bool {
+	for i, orderBy := range orderBys {
+		comparison := memoryOrderBy(orderBy, row[orderBy.Field], cursorValues[i])
+		if comparison != 0 {
+			return comparison > 0
+		}
+	}
+	return false
+}
+
+// memoryTruth is the result of a predicate under the three-valued logic of
+// SQL, where a comparison with NULL is neither true nor false
+type memoryTruth int
+
+const (
+	memoryFalse   memoryTruth = iota // The predicate doesn't match the row
+	memoryTrue                       // The predicate matches the row
+	memoryUnknown                    // The predicate compares a NULL
+)
+
+// memoryTruthOf returns the memoryTruth of b
+func memoryTruthOf(b bool) memoryTruth {
+	if b {
+		return memoryTrue
+	}
+	return memoryFalse
+}
+
+// not returns the negation of the memoryTruth, where the negation of
+// memoryUnknown is also memoryUnknown
+func (t memoryTruth) not() memoryTruth {
+	switch t {
+	case memoryTrue:
+		return memoryFalse
+	case memoryFalse:
+		return memoryTrue
+	}
+	return memoryUnknown
+}
+
+// memoryMatchesAll returns if a row is matched by all of the predicates
+func memoryMatchesAll(predicates []Predicate, row memoryRow) bool {
+	return memoryEvaluateAll(predicates, row) == memoryTrue
+}
+
+// memoryMatches returns if a row is matched by a predicate
+func memoryMatches(predicate Predicate, row memoryRow) bool {
+	return memoryEvaluate(predicate, row) == memoryTrue
+}
+
+// memoryEvaluateAll returns the memoryTruth of all of the predicates of a
+// row being true, as the predicates of a WHERE clause are joined by AND
+func memoryEvaluateAll(predicates []Predicate, row memoryRow) memoryTruth {
+	truth := memoryTrue
+	for _, predicate := range predicates {
+		switch memoryEvaluate(predicate, row) {
+		case memoryFalse:
+			return memoryFalse
+		case memoryUnknown:
+			truth = memoryUnknown
+		}
+	}
+	return truth
+}
+
+// memoryEvaluate returns the memoryTruth of a predicate of a row
+func memoryEvaluate(predicate Predicate, row memoryRow) memoryTruth {
+	value := row[predicate.Field]
+	switch predicate.PredicateType {
+	case WHERE_AND:
+		return memoryEvaluateAll(predicate.Predicates, row)
+	case WHERE_OR:
+		truth := memoryFalse
+		for _, child := range predicate.Predicates {
+			switch memoryEvaluate(child, row) {
+			case memoryTrue:
+				return memoryTrue
+			case memoryUnknown:
+				truth = memoryUnknown
+			}
+		}
+		return truth
+	case WHERE_NOT:
+		return memoryEvaluateAll(predicate.Predicates, row).not()
+	case WHERE_IS_NULL:
+		return memoryTruthOf(memoryNormalize(value) == nil)
+	case WHERE_IS_NOT_NULL:
+		return memoryTruthOf(memoryNormalize(value) != nil)
+	case WHERE_IS_DISTINCT_FROM:
+		aNull, bNull := memoryNormalize(value) == nil, memoryNormalize(predicate.Values[0]) == nil
+		if aNull || bNull {
+			return memoryTruthOf(aNull != bNull)
+		}
+		comparison, ok := memoryCompare(value, predicate.Values[0])
+		return memoryTruthOf(!ok || comparison != 0)
+	}
+
+	// Every other operator is unknown for a NULL operand, other than the
+	// values of an IN, which are handled below
+	if memoryNormalize(value) == nil {
+		return memoryUnknown
+	}
+	if predicate.PredicateType != WHERE_IN && predicate.PredicateType != WHERE_NOT_IN {
+		for _, predicateValue := range predicate.Values {
+			if memoryNormalize(predicateValue) == nil {
+				return memoryUnknown
+			}
+		}
+	}
+	switch predicate.PredicateType {
+	case WHERE_IN, WHERE_NOT_IN:
+		// A NULL in the list makes a row that isn't found unknown
+		truth := memoryFalse
+		for _, predicateValue := range predicate.Values {
+			if memoryNormalize(predicateValue) == nil {
+				truth = memoryUnknown
+			} else if comparison, ok := memoryCompare(value, predicateValue); ok && comparison == 0 {
+				truth = memoryTrue
+				break
+			}
+		}
+		if predicate.PredicateType == WHERE_NOT_IN {
+			return truth.not()
+		}
+		return truth
+	case WHERE_LIKE, WHERE_ILIKE, WHERE_NOT_LIKE:
+		str, ok := memoryNormalize(value).(string)
+		pattern, patternOk := memoryNormalize(predicate.Values[0]).(string)
+		if !ok || !patternOk {
+			return memoryFalse
+		}
+		expression := likeToRegexp(pattern)
+		if predicate.PredicateType == WHERE_ILIKE {
+			expression = regexp.MustCompile("(?i)" + expression.String())
+		}
+		return memoryTruthOf(expression.MatchString(str) == (predicate.PredicateType != WHERE_NOT_LIKE))
+	case WHERE_REGEX, WHERE_IREGEX:
+		str, ok := memoryNormalize(value).(string)
+		pattern, patternOk := memoryNormalize(predicate.Values[0]).(string)
+		if !ok || !patternOk {
+			return memoryFalse
+		}
+		if predicate.PredicateType == WHERE_IREGEX {
+			pattern = "(?i)" + pattern
+		}
+		expression, err := regexp.Compile(pattern)
+		return memoryTruthOf(err == nil && expression.MatchString(str))
+	case WHERE_BETWEEN:
+		low, lowOk := memoryCompare(value, predicate.Values[0])
+		high, highOk := memoryCompare(value, predicate.Values[1])
+		return memoryTruthOf(lowOk && highOk && low >= 0 && high <= 0)
+	case WHERE_ANY:
+		elements, ok := memorySlice(predicate.Values[0])
+		return memoryTruthOf(ok && memoryContainsAny(elements, []interface{}{value}))
+	case WHERE_CONTAINS, WHERE_CONTAINED_BY, WHERE_OVERLAPS:
+		return memoryTruthOf(memoryMatchesCollection(predicate, value))
+	case WHERE_HAS_KEY, WHERE_JSON_TEXT_EQUAL:
+		return memoryTruthOf(memoryMatchesJSONKey(predicate, value))
+	case WHERE_MATCHES:
+		return memoryTruthOf(memoryRank(predicate.textSearch(), row) > 0)
+	}
+
+	comparison, ok := memoryCompare(value, predicate.Values[0])
+	if !ok {
+		return memoryFalse
+	}
+	switch predicate.PredicateType {
+	case WHERE_EQUAL:
+		return memoryTruthOf(comparison == 0)
+	case WHERE_NOT_EQUAL:
+		return memoryTruthOf(comparison != 0)
+	case WHERE_GREATER_THAN:
+		return memoryTruthOf(comparison > 0)
+	case WHERE_GREATER_THAN_OR_EQUAL_TO:
+		return memoryTruthOf(comparison >= 0)
+	case WHERE_LESS_THAN:
+		return memoryTruthOf(comparison < 0)
+	case WHERE_LESS_THAN_OR_EQUAL_TO:
+		return memoryTruthOf(comparison <= 0)
+	}
+	return memoryFalse
+}
+
+// memoryRank returns the relevance of a row to a search, as the number of times
+// the words of the best matching alternative of the query appear in the field.
+// Rows that don't match the search have a relevance of zero.
+func memoryRank(search TextSearch, row memoryRow) float64 {
+	str, ok := memoryNormalize(row[search.Field]).(string)
+	if !ok {
+		return 0
+	}
+	counts := make(map[string]int)
+	for _, word := range textSearchWords(str) {
+		counts[word]++
+	}
+
+	alternatives, excluded := search.textSearchTerms()
+	for _, word := range excluded {
+		if counts[word] > 0 {
+			return 0
+		}
+	}
+	rank := 0
+	for _, terms := range alternatives {
+		alternativeRank := 0
+		for _, term := range terms {
+			if counts[term] == 0 {
+				alternativeRank = 0
+				break
+			}
+			alternativeRank += counts[term]
+		}
+		if alternativeRank > rank {
+			rank = alternativeRank
+		}
+	}
+	return float64(rank)
+}
+
+// memoryMatchesCollection returns if the array or JSON value is matched by a
+// WHERE_CONTAINS, WHERE_CONTAINED_BY or WHERE_OVERLAPS predicate
+func memoryMatchesCollection(predicate Predicate, value interface{}) bool {
+	// Arrays
+	if elements, ok := memorySlice(value); ok {
+		predicateElements, ok := memorySlice(predicate.Values[0])
+		if !ok {
+			return false
+		}
+		switch predicate.PredicateType {
+		case WHERE_CONTAINS:
+			return memoryContainsAll(elements, predicateElements)
+		case WHERE_CONTAINED_BY:
+			return memoryContainsAll(predicateElements, elements)
+		}
+		return memoryContainsAny(elements, predicateElements)
+	}
+
+	// JSON
+	document, ok := memoryJSON(value)
+	predicateDocument, predicateOk := memoryJSON(predicate.Values[0])
+	if !ok || !predicateOk {
+		return false
+	}
+	switch predicate.PredicateType {
+	case WHERE_CONTAINS:
+		return memoryJSONContains(document, predicateDocument)
+	case WHERE_CONTAINED_BY:
+		return memoryJSONContains(predicateDocument, document)
+	}
+	return false
+}
+
+// memoryMatchesJSONKey returns if the JSON value is matched by a
+// WHERE_HAS_KEY or WHERE_JSON_TEXT_EQUAL predicate
+func memoryMatchesJSONKey(predicate Predicate, value interface{}) bool {
+	document, ok := memoryJSON(value)
+	key, keyOk := memoryNormalize(predicate.Values[0]).(string)
+	if !ok || !keyOk {
+		return false
+	}
+
+	// As in Postgres, `?` also matches the strings of an array
+	if predicate.PredicateType == WHERE_HAS_KEY {
+		switch tv := document.(type) {
+		case map[string]interface{}:
+			_, ok := tv[key]
+			return ok
+		case []interface{}:
+			for _, element := range tv {
+				if element == key {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	object, ok := document.(map[string]interface{})
+	if !ok || object[key] == nil {
+		return false
+	}
+	text, ok := object[key].(string)
+	if !ok {
+		encoded, _ := json.Marshal(object[key])
+		text = string(encoded)
+	}
+	comparison, ok := memoryCompare(text, predicate.Values[1])
+	return ok && comparison == 0
+}
+
+// memorySlice returns the elements of a value that is a slice, or a
+// pointer to a slice, other than a []byte
+func memorySlice(value interface{}) ([]interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	for rv.IsValid() && rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	elements := make([]interface{}, rv.Len())
+	for i := range elements {
+		elements[i] = rv.Index(i).Interface()
+	}
+	return elements, true
+}
+
+// memoryContainsAll returns if every element of b is equal to an element of a
+func memoryContainsAll(a []interface{}, b []interface{}) bool {
+	for _, element := range b {
+		if !memoryContainsAny(a, []interface{}{element}) {
+			return false
+		}
+	}
+	return true
+}
+
+// memoryContainsAny returns if any element of b is equal to an element of a
+func memoryContainsAny(a []interface{}, b []interface{}) bool {
+	for _, aElement := range a {
+		for _, bElement := range b {
+			if comparison, ok := memoryCompare(aElement, bElement); ok && comparison == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// memoryJSON decodes a value that holds a JSON document
+func memoryJSON(value interface{}) (interface{}, bool) {
+	str, ok := memoryNormalize(value).(string)
+	if !ok {
+		return nil, false
+	}
+	var document interface{}
+	err := json.Unmarshal([]byte(str), &document)
+	return document, err == nil
+}
+
+// memoryJSONContains returns if the JSON document a contains the JSON
+// document b, as the Postgres `@>` operator does for JSONB
+func memoryJSONContains(a interface{}, b interface{}) bool {
+	switch bv := b.(type) {
+	case map[string]interface{}:
+		av, ok := a.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range bv {
+			if _, ok := av[key]; !ok || !memoryJSONContains(av[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		av, ok := a.([]interface{})
+		if !ok {
+			return false
+		}
+	FindElement:
+		for _, bElement := range bv {
+			for _, aElement := range av {
+				if memoryJSONContains(aElement, bElement) {
+					continue FindElement
+				}
+			}
+			return false
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// likeToRegexp converts a SQL LIKE pattern to a regular expression, where
+// `%` matches any string, `_` matches any character, and `\` escapes
+func likeToRegexp(pattern string) *regexp.Regexp {
+	var expression bytes.Buffer
+	expression.WriteString("(?s)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expression.WriteString(".*")
+		case r == '_':
+			expression.WriteString(".")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expression.WriteString("$")
+	return regexp.MustCompile(expression.String())
+}
@@ -0,0 +1,420 @@
+package surf_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-carrot/surf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/guregu/null.v3"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryModels returns a modelBuilder that builds a surf.MemoryModel
+func memoryModels(store *surf.MemoryStore) modelBuilder {
+	return func(config surf.Configuration) surf.Model {
+		return &surf.MemoryModel{Store: store, Config: config}
+	}
+}
+
+// ================================
+// ========== Test Suite ==========
+// ================================
+
+type MemoryModelTestSuite struct {
+	ModelTestSuite
+}
+
+func (suite *MemoryModelTestSuite) SetupTest() {
+	suite.models = memoryModels(&surf.MemoryStore{})
+}
+
+// insertAnimals inserts an Animal for each name, with an age
+// of the length of the name
+func (suite *MemoryModelTestSuite) insertAnimals(names ...string) []*Animal {
+	var animals []*Animal
+	for _, name := range names {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = name
+		animal.Slug = name
+		animal.Age = len(name)
+		err := animal.Insert()
+		assert.Nil(suite.T(), err)
+		animals = append(animals, animal)
+	}
+	return animals
+}
+
+// fetchNames runs a BulkFetch of Animals and returns their names
+func (suite *MemoryModelTestSuite) fetchNames(config surf.BulkFetchConfig) []string {
+	animals, err := NewAnimalWith(suite.models).BulkFetch(config, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	names := make([]string, 0)
+	for _, animal := range animals {
+		names = append(names, animal.(*Animal).Name)
+	}
+	return names
+}
+
+func (suite *MemoryModelTestSuite) TestInsert() {
+	animals := suite.insertAnimals("Rigby", "Luna")
+	assert.Equal(suite.T(), int64(1), animals[0].Id)
+	assert.Equal(suite.T(), int64(2), animals[1].Id)
+
+	// Cause a conflict on a UniqueIdentifier
+	rigbyTwo := NewAnimalWith(suite.models)
+	rigbyTwo.Name = "Rigby Two"
+	rigbyTwo.Slug = "Rigby"
+	err := rigbyTwo.Insert()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MemoryModelTestSuite) TestBulkInsert() {
+	var animals []surf.Model
+	for _, name := range []string{"Rigby", "Luna", "Rae"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = name
+		animal.Slug = name
+		animals = append(animals, animal)
+	}
+	err := NewAnimalWith(suite.models).Model.(surf.BulkInserter).BulkInsert(animals)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), animals[2].(*Animal).Id)
+	assert.Equal(suite.T(), []string{"Rigby", "Luna", "Rae"}, suite.fetchNames(surf.BulkFetchConfig{Limit: 10}))
+
+	// Insert toys, which should expand their owners
+	tennisBall := NewToyWith(suite.models)
+	tennisBall.Name = "tennis ball"
+	tennisBall.OwnerId = 2
+	err = NewToyWith(suite.models).Model.(surf.BulkInserter).BulkInsert([]surf.Model{tennisBall})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Luna", tennisBall.Owner.Name)
+
+	// Nothing is inserted in the event of a conflict
+	norbert := NewAnimalWith(suite.models)
+	norbert.Name = "Norbert"
+	norbert.Slug = "Norbert"
+	rigbyTwo := NewAnimalWith(suite.models)
+	rigbyTwo.Name = "Rigby Two"
+	rigbyTwo.Slug = "Rigby"
+	err = NewAnimalWith(suite.models).Model.(surf.BulkInserter).BulkInsert([]surf.Model{norbert, rigbyTwo})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"Rigby", "Luna", "Rae"}, suite.fetchNames(surf.BulkFetchConfig{Limit: 10}))
+
+	// Ids continue on after the failed insert
+	luna := suite.insertAnimals("Norbert")[0]
+	assert.Equal(suite.T(), int64(4), luna.Id)
+}
+
+func (suite *MemoryModelTestSuite) TestLoad() {
+	rigby := suite.insertAnimals("Rigby")[0]
+
+	// Verify it loads from id
+	rigbyIdLoad := NewAnimalWith(suite.models)
+	rigbyIdLoad.Id = rigby.Id
+	err := rigbyIdLoad.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Rigby", rigbyIdLoad.Name)
+	assert.Equal(suite.T(), 5, rigbyIdLoad.Age)
+
+	// Verify it loads from slug
+	rigbySlugLoad := NewAnimalWith(suite.models)
+	rigbySlugLoad.Slug = rigby.Slug
+	err = rigbySlugLoad.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), rigby.Id, rigbySlugLoad.Id)
+
+	// Make sure an error is thrown when nothing is set
+	err = NewAnimalWith(suite.models).Load()
+	assert.NotNil(suite.T(), err)
+
+	// Make sure an error is thrown when trying to load something that doesn't exist
+	dummyAnimal := NewAnimalWith(suite.models)
+	dummyAnimal.Slug = "wow-cool-cat"
+	err = dummyAnimal.Load()
+	assert.Equal(suite.T(), sql.ErrNoRows, err)
+}
+
+func (suite *MemoryModelTestSuite) TestUpdate() {
+	animals := suite.insertAnimals("Rigby", "Norbert")
+
+	// Update
+	rigby := animals[0]
+	rigby.Age = 4
+	err := rigby.Update()
+	assert.Nil(suite.T(), err)
+
+	rigbyVerification := NewAnimalWith(suite.models)
+	rigbyVerification.Id = rigby.Id
+	rigbyVerification.Load()
+	assert.Equal(suite.T(), 4, rigbyVerification.Age)
+
+	// Update and cause conflict, which leaves the row untouched
+	norbert := animals[1]
+	norbert.Slug = "Rigby"
+	norbert.Age = 100
+	err = norbert.Update()
+	assert.NotNil(suite.T(), err)
+
+	norbertVerification := NewAnimalWith(suite.models)
+	norbertVerification.Id = norbert.Id
+	norbertVerification.Load()
+	assert.Equal(suite.T(), 7, norbertVerification.Age)
+
+	// Update something that doesn't exist
+	dummyAnimal := NewAnimalWith(suite.models)
+	dummyAnimal.Slug = "wow-cool-cat"
+	err = dummyAnimal.Update()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MemoryModelTestSuite) TestTextSearch() {
+	// Create some Animals
+	for _, name := range []string{"Big Dog", "Small Dog", "Big Big Cat", "Bird"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = name
+		animal.Slug = strings.ToLower(strings.Replace(name, " ", "-", -1))
+		animal.Insert()
+	}
+
+	// searchSlugs returns the slugs of the animals that match search,
+	// ordered by their relevance
+	searchSlugs := func(search surf.TextSearch) []string {
+		models, err := NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+			Limit:      10,
+			OrderBys:   []surf.OrderBy{search.OrderBy(), {Field: "slug", Type: surf.ORDER_BY_ASC}},
+			Predicates: []surf.Predicate{search.Predicate()},
+		}, func() surf.Model {
+			return NewAnimalWith(suite.models)
+		})
+		assert.Nil(suite.T(), err)
+		var slugs []string
+		for _, model := range models {
+			slugs = append(slugs, model.(*Animal).Slug)
+		}
+		return slugs
+	}
+
+	assert.Equal(suite.T(), []string{"big-big-cat", "big-dog"}, searchSlugs(surf.TextSearch{Field: "name", Query: "big"}))
+	assert.Equal(suite.T(), []string{"big-dog", "small-dog"}, searchSlugs(surf.TextSearch{Field: "name", Query: "dog", Config: "english"}))
+	assert.Equal(suite.T(), []string{"big-dog"}, searchSlugs(surf.TextSearch{Field: "name", Query: "dog -small"}))
+	assert.Equal(suite.T(), []string{"big-big-cat", "bird"}, searchSlugs(surf.TextSearch{Field: "name", Query: "cat or bird"}))
+	assert.Nil(suite.T(), searchSlugs(surf.TextSearch{Field: "name", Query: "fish"}))
+
+	// Keyset pagination can't seek past a rank
+	_, err := NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+		Keyset:   true,
+		OrderBys: []surf.OrderBy{surf.TextSearch{Field: "name", Query: "big"}.OrderBy()},
+	}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.NotNil(suite.T(), err)
+
+	// Malformed predicates
+	assert.Panics(suite.T(), func() {
+		NewAnimalWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{
+			Predicates: []surf.Predicate{{Field: "name", PredicateType: surf.WHERE_MATCHES, Values: []interface{}{1}}},
+		})
+	})
+}
+
+func (suite *MemoryModelTestSuite) TestIdentifiers() {
+	// Unknown fields are an InvalidColumnError
+	predicates := []surf.Predicate{{Field: "1 = 1 OR name", PredicateType: surf.WHERE_IS_NOT_NULL}}
+	buildAnimal := func() surf.Model {
+		return NewAnimalWith(suite.models)
+	}
+	var invalidColumnErr *surf.InvalidColumnError
+	_, err := NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{Predicates: predicates}, buildAnimal)
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	assert.Equal(suite.T(), "animals", invalidColumnErr.TableName)
+	assert.Equal(suite.T(), "1 = 1 OR name", invalidColumnErr.Column)
+	assert.Equal(suite.T(), "filter", invalidColumnErr.Action)
+	assert.Equal(suite.T(), "Could not filter table 'animals' by the invalid column '1 = 1 OR name'", err.Error())
+	_, err = NewAnimalWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{Predicates: predicates})
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	_, err = NewAnimalWith(suite.models).Model.(surf.Counter).Exists(surf.BulkFetchConfig{Predicates: []surf.Predicate{surf.Or(predicates...)}})
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	_, err = NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+		OrderBys: []surf.OrderBy{{Field: "age; DROP TABLE animals"}},
+	}, buildAnimal)
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	assert.Equal(suite.T(), "order", invalidColumnErr.Action)
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkUpdate([]surf.Predicate{{Field: "age", PredicateType: surf.WHERE_IS_NOT_NULL}},
+		map[string]interface{}{"owner": 1})
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	assert.Equal(suite.T(), "Could not update table 'animals' with the invalid column 'owner'", err.Error())
+}
+
+func (suite *MemoryModelTestSuite) TestDelete() {
+	rigby := suite.insertAnimals("Rigby")[0]
+
+	// Delete
+	err := rigby.Delete()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{}, suite.fetchNames(surf.BulkFetchConfig{Limit: 10}))
+
+	// Try to delete an animal that doesn't exist
+	err = rigby.Delete()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MemoryModelTestSuite) TestPredicates() {
+	suite.insertAnimals("Luna", "Rae", "Rigby", "Norbert")
+
+	predicate := func(field string, predicateType surf.PredicateType, values ...interface{}) []string {
+		return suite.fetchNames(surf.BulkFetchConfig{
+			Limit:      10,
+			OrderBys:   []surf.OrderBy{{Field: "id", Type: surf.ORDER_BY_ASC}},
+			Predicates: []surf.Predicate{{Field: field, PredicateType: predicateType, Values: values}},
+		})
+	}
+	assert.Equal(suite.T(), []string{"Luna", "Rae", "Rigby", "Norbert"}, predicate("name", surf.WHERE_IS_NOT_NULL))
+	assert.Equal(suite.T(), []string{}, predicate("name", surf.WHERE_IS_NULL))
+	assert.Equal(suite.T(), []string{"Luna", "Rigby"}, predicate("name", surf.WHERE_IN, "Luna", "Rigby"))
+	assert.Equal(suite.T(), []string{"Rae", "Norbert"}, predicate("name", surf.WHERE_NOT_IN, "Luna", "Rigby"))
+	assert.Equal(suite.T(), []string{"Rae", "Rigby"}, predicate("name", surf.WHERE_LIKE, "R%"))
+	assert.Equal(suite.T(), []string{"Rae"}, predicate("name", surf.WHERE_LIKE, "R_e"))
+	assert.Equal(suite.T(), []string{"Luna"}, predicate("age", surf.WHERE_EQUAL, 4))
+	assert.Equal(suite.T(), []string{"Luna", "Rae", "Norbert"}, predicate("age", surf.WHERE_NOT_EQUAL, int64(5)))
+	assert.Equal(suite.T(), []string{"Rigby", "Norbert"}, predicate("age", surf.WHERE_GREATER_THAN, 4))
+	assert.Equal(suite.T(), []string{"Luna", "Rigby", "Norbert"}, predicate("age", surf.WHERE_GREATER_THAN_OR_EQUAL_TO, 4.0))
+	assert.Equal(suite.T(), []string{"Rae"}, predicate("age", surf.WHERE_LESS_THAN, 4))
+	assert.Equal(suite.T(), []string{"Luna", "Rae"}, predicate("age", surf.WHERE_LESS_THAN_OR_EQUAL_TO, 4))
+
+	// Multiple predicates
+	names := suite.fetchNames(surf.BulkFetchConfig{
+		Limit: 10,
+		Predicates: []surf.Predicate{
+			{Field: "name", PredicateType: surf.WHERE_NOT_EQUAL, Values: []interface{}{"Luna"}},
+			{Field: "name", PredicateType: surf.WHERE_NOT_EQUAL, Values: []interface{}{"Rae"}},
+		},
+	})
+	assert.Equal(suite.T(), []string{"Rigby", "Norbert"}, names)
+
+	// Invalid column
+	_, err := NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+		Limit:      10,
+		Predicates: []surf.Predicate{{Field: "helloworld", PredicateType: surf.WHERE_IS_NULL}},
+	}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.NotNil(suite.T(), err)
+
+	// Malformed predicates panic, as they do in the SQL models
+	assert.Panics(suite.T(), func() { predicate("name", surf.WHERE_EQUAL) })
+	assert.Panics(suite.T(), func() { predicate("name", surf.WHERE_IN) })
+	assert.Panics(suite.T(), func() { predicate("name", surf.WHERE_IS_NULL, 1) })
+	assert.Panics(suite.T(), func() { predicate("name", 9999, 1) })
+}
+
+func (suite *MemoryModelTestSuite) TestOrderBy() {
+	suite.insertAnimals("Luna", "Rae", "Rigby", "Norbert", "Ada")
+
+	config := surf.BulkFetchConfig{Limit: 10}
+	config.ConsumeSortQuery("-age,name")
+	assert.Equal(suite.T(), []string{"Norbert", "Rigby", "Luna", "Ada", "Rae"}, suite.fetchNames(config))
+
+	// Limit + Offset
+	config.Limit = 2
+	config.Offset = 1
+	assert.Equal(suite.T(), []string{"Rigby", "Luna"}, suite.fetchNames(config))
+	config.Offset = 10
+	assert.Equal(suite.T(), []string{}, suite.fetchNames(config))
+
+	// Invalid column
+	config.ConsumeSortQuery("helloworld")
+	_, err := NewAnimalWith(suite.models).BulkFetch(config, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MemoryModelTestSuite) TestNestedModel() {
+	cat := suite.insertAnimals("Luna")[0]
+
+	// Create toys, which should expand their owners
+	tennisBall := NewToyWith(suite.models)
+	tennisBall.Name = "tennis ball"
+	tennisBall.OwnerId = cat.Id
+	err := tennisBall.Insert()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Luna", tennisBall.Owner.Name)
+	assert.Nil(suite.T(), tennisBall.SecondOwner)
+
+	sock := NewToyWith(suite.models)
+	sock.Name = "sock"
+	sock.OwnerId = cat.Id
+	sock.SecondOwnerId = null.IntFrom(cat.Id)
+	err = sock.Insert()
+	assert.Nil(suite.T(), err)
+
+	// Load all toys, ordering by a nullable column
+	config := surf.BulkFetchConfig{Limit: 10}
+	config.ConsumeSortQuery("second_owner")
+	toys, err := NewToyWith(suite.models).BulkFetch(config, func() surf.Model {
+		return NewToyWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(toys))
+	assert.Equal(suite.T(), "sock", toys[0].(*Toy).Name)
+	assert.Equal(suite.T(), "Luna", toys[0].(*Toy).SecondOwner.Name)
+	assert.Equal(suite.T(), "tennis ball", toys[1].(*Toy).Name)
+	assert.Nil(suite.T(), toys[1].(*Toy).SecondOwner)
+	assert.Equal(suite.T(), "Luna", toys[1].(*Toy).Owner.Name)
+}
+
+func (suite *MemoryModelTestSuite) TestContext() {
+	rigby := suite.insertAnimals("Rigby")[0]
+
+	// Nothing should run with a cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rigby.Age = 100
+	assert.Equal(suite.T(), context.Canceled, NewAnimalWith(suite.models).Model.(surf.ContextModel).InsertContext(ctx))
+	assert.Equal(suite.T(), context.Canceled, rigby.Model.(surf.ContextModel).LoadContext(ctx))
+	assert.Equal(suite.T(), context.Canceled, rigby.Model.(surf.ContextModel).UpdateContext(ctx))
+	assert.Equal(suite.T(), context.Canceled, rigby.Model.(surf.ContextModel).DeleteContext(ctx))
+	_, err := NewAnimalWith(suite.models).Model.(surf.ContextModel).BulkFetchContext(ctx, surf.BulkFetchConfig{Limit: 10}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.Equal(suite.T(), context.Canceled, err)
+
+	// Verify nothing changed
+	rigbyVerification := NewAnimalWith(suite.models)
+	rigbyVerification.Id = rigby.Id
+	err = rigbyVerification.Model.(surf.ContextModel).LoadContext(context.Background())
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 5, rigbyVerification.Age)
+}
+
+func (suite *MemoryModelTestSuite) TestConcurrency() {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			animal := NewAnimalWith(suite.models)
+			animal.Name = "Animal"
+			animal.Slug = "animal-" + strconv.Itoa(i)
+			assert.Nil(suite.T(), animal.Insert())
+			animal.Age = i
+			assert.Nil(suite.T(), animal.Update())
+			suite.fetchNames(surf.BulkFetchConfig{Limit: 100})
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(suite.T(), 20, len(suite.fetchNames(surf.BulkFetchConfig{Limit: 100})))
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestMemoryModelTestSuite(t *testing.T) {
+	suite.Run(t, &MemoryModelTestSuite{ModelTestSuite: ModelTestSuite{distinctFrom: true, postgres: true}})
+}
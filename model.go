@@ -1,5 +1,9 @@
 package surf
 
+import (
+	"context"
+)
+
 // Model is the interface that defines the type
 // that will be embedded on models
 type Model interface {
@@ -11,10 +15,163 @@ type Model interface {
 	GetConfiguration() *Configuration
 }
 
+// ContextModel is the interface that defines the operations of a Model
+// that are bound to a context.Context, so cancellation and deadlines
+// reach the database.
+//
+// The context is also used to load any foreign references.
+type ContextModel interface {
+	InsertContext(context.Context) error
+	LoadContext(context.Context) error
+	UpdateContext(context.Context) error
+	DeleteContext(context.Context) error
+	BulkFetchContext(context.Context, BulkFetchConfig, BuildModel) ([]Model, error)
+}
+
+// BulkInserter is the interface of a Model that can insert
+// many models at once
+type BulkInserter interface {
+	BulkInsert([]Model) error
+	BulkInsertContext(context.Context, []Model) error
+}
+
+// Upserter is the interface of a Model that can insert a model, or
+// resolve a conflict with an existing row
+type Upserter interface {
+	Upsert(UpsertConfig) error
+	UpsertContext(context.Context, UpsertConfig) error
+}
+
+// BulkWriter is the interface of a Model that can update or delete
+// every row that matches a set of predicates
+type BulkWriter interface {
+	BulkUpdate([]Predicate, map[string]interface{}) (int64, error)
+	BulkUpdateContext(context.Context, []Predicate, map[string]interface{}) (int64, error)
+	BulkDelete([]Predicate) (int64, error)
+	BulkDeleteContext(context.Context, []Predicate) (int64, error)
+}
+
+// Counter is the interface of a Model that can count the rows
+// that match a set of predicates
+type Counter interface {
+	Count(BulkFetchConfig) (int64, error)
+	CountContext(context.Context, BulkFetchConfig) (int64, error)
+	Exists(BulkFetchConfig) (bool, error)
+	ExistsContext(context.Context, BulkFetchConfig) (bool, error)
+}
+
+// Aggregator is the interface of a Model that can run aggregates
+// over its table
+type Aggregator interface {
+	Aggregate(AggregateConfig) ([]AggregateRow, error)
+	AggregateContext(context.Context, AggregateConfig) ([]AggregateRow, error)
+}
+
+// Streamer is the interface of a Model that can read a bulk fetch
+// one model at a time
+type Streamer interface {
+	Stream(BulkFetchConfig, BuildModel, StreamConfig, func(Model) error) error
+	StreamContext(context.Context, BulkFetchConfig, BuildModel, StreamConfig, func(Model) error) error
+}
+
+// FieldLoader is the interface of a Model that can load only
+// some of its fields
+type FieldLoader interface {
+	LoadFields([]string) error
+	LoadFieldsContext(context.Context, []string) error
+}
+
+// SoftDeleter is the interface of a Model that can restore or
+// finalize a soft delete
+type SoftDeleter interface {
+	HardDelete() error
+	HardDeleteContext(context.Context) error
+	BulkHardDelete([]Predicate) (int64, error)
+	BulkHardDeleteContext(context.Context, []Predicate) (int64, error)
+	Restore() error
+	RestoreContext(context.Context) error
+}
+
+// fullModel is the interface of a Model that implements
+// every optional interface
+type fullModel interface {
+	Model
+	ContextModel
+	BulkInserter
+	Upserter
+	BulkWriter
+	Counter
+	Aggregator
+	Streamer
+	FieldLoader
+	SoftDeleter
+}
+
 // Configuration is the metadata to be attached to a model
 type Configuration struct {
 	TableName string
 	Fields    []Field
+
+	// OrderExpressions are the SQL expressions that may be ordered by, keyed
+	// by the name that is used as the Field of an OrderBy, such as
+	// `"lower_name": "lower(name)"`
+	OrderExpressions map[string]string
+
+	// DefaultLimit is the Limit of a NewBulkFetchConfig without a `limit`
+	// query parameter, which defaults to 20
+	DefaultLimit int
+
+	// MaxLimit is the largest Limit of a NewBulkFetchConfig, which
+	// defaults to 100
+	MaxLimit int
+
+	// DeletedAtField is the Name of a `null.Time` or `*time.Time` field that
+	// Delete sets to the current time, instead of deleting the row.  Reads
+	// then exclude the rows that are soft deleted, unless their context is
+	// from WithDeleted or OnlyDeleted.
+	DeletedAtField string
+
+	// loaded are the names of the fields that were last read from the database
+	loaded []string
+
+	// snapshot are the values of every field that has been read from the
+	// database, as they were last read, keyed by the name of each field
+	snapshot map[string]interface{}
+}
+
+// LoadedFields returns the names of the fields that were read from the
+// database by the last Load, BulkFetch, Insert or Update of the model,
+// which is nil in the event that the model hasn't been read yet
+func (c *Configuration) LoadedFields() []string {
+	return c.loaded
+}
+
+// IsLoaded returns if the field of name was read from the database by the
+// last Load, BulkFetch, Insert or Update of the model
+func (c *Configuration) IsLoaded(name string) bool {
+	for _, loaded := range c.loaded {
+		if loaded == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Changed returns the names of the fields whose values have changed since
+// the last Load, BulkFetch, Insert or Update of the model
+//
+// A field that was only read by an earlier Load or BulkFetch is compared to
+// the value it was read with, and a field that has never been read is always
+// changed, so that a model that was partially loaded writes every field that
+// it didn't read.
+func (c *Configuration) Changed() []string {
+	var changed []string
+	for _, field := range c.Fields {
+		if c.isChanged(field) {
+			changed = append(changed, field.Name)
+		}
+	}
+	return changed
 }
 
 // Field is the definition of a single value in a model
@@ -24,6 +181,9 @@ type Field struct {
 	Insertable       bool
 	Updatable        bool
 	UniqueIdentifier bool
+	Filterable       bool
+	Sortable         bool
+	Version          bool
 	SkipValidation   bool
 	GetReference     func() (BuildModel, string)
 	SetReference     func(Model) error
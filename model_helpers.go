@@ -1,10 +1,13 @@
 package surf
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"gopkg.in/guregu/null.v3"
+	"reflect"
+	"time"
 )
 
 // getUniqueIdentifier Returns the unique identifier that this model will
@@ -44,7 +47,7 @@ func getUniqueIdentifier(w Model) (Field, error) {
 }
 
 // expandForeign expands all foreign references for a single Model
-func expandForeign(model Model) error {
+func expandForeign(ctx context.Context, model Model) error {
 	// Load all foreign references
 	for _, field := range model.GetConfiguration().Fields {
 
@@ -72,8 +75,11 @@ func expandForeign(model Model) error {
 				}
 			}
 
-			// Load
-			err := model.Load()
+			// Load, skipping a foreign reference that is soft deleted
+			err := loadContext(ctx, model)
+			if err == sql.ErrNoRows && model.GetConfiguration().DeletedAtField != "" {
+				continue
+			}
 			if err != nil {
 				return err
 			}
@@ -89,14 +95,82 @@ func expandForeign(model Model) error {
 	return nil
 }
 
+// embeddedModel returns the Model that is embedded in model (as in
+// `type Animal struct { surf.Model }`), along with if there is one
+func embeddedModel(model Model) (Model, bool) {
+	value := reflect.ValueOf(model)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, false
+	}
+	field, ok := value.Type().FieldByName("Model")
+	if !ok || !field.Anonymous || field.Type != reflect.TypeOf((*Model)(nil)).Elem() {
+		return nil, false
+	}
+	embedded := value.FieldByIndex(field.Index)
+	if embedded.IsNil() {
+		return nil, false
+	}
+	return embedded.Interface().(Model), true
+}
+
+// asContextModel returns model as a ContextModel, looking through the
+// Model that is embedded in it in the event that model itself isn't one
+func asContextModel(model Model) (ContextModel, bool) {
+	if contextModel, ok := model.(ContextModel); ok {
+		return contextModel, true
+	}
+	if embedded, ok := embeddedModel(model); ok {
+		return asContextModel(embedded)
+	}
+	return nil, false
+}
+
+// engineModel is a Model whose queries are run by a sqlEngine
+type engineModel interface {
+	engine() sqlEngine
+}
+
+// modelDialect returns the Dialect of model, looking through the Model that
+// is embedded in it, along with if model is run by a sqlEngine
+func modelDialect(model Model) (Dialect, bool) {
+	if engineModel, ok := model.(engineModel); ok {
+		return engineModel.engine().Dialect, true
+	}
+	if embedded, ok := embeddedModel(model); ok {
+		return modelDialect(embedded)
+	}
+	return nil, false
+}
+
+// loadContext loads model with ctx in the event that it is a ContextModel,
+// or through its Load otherwise
+func loadContext(ctx context.Context, model Model) error {
+	if contextModel, ok := asContextModel(model); ok {
+		return contextModel.LoadContext(ctx)
+	}
+	return model.Load()
+}
+
+// bulkFetchContext runs a bulk fetch of model with ctx in the event that it
+// is a ContextModel, or through its BulkFetch otherwise
+func bulkFetchContext(ctx context.Context, model Model, fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	if contextModel, ok := asContextModel(model); ok {
+		return contextModel.BulkFetchContext(ctx, fetchConfig, buildModel)
+	}
+	return model.BulkFetch(fetchConfig, buildModel)
+}
+
 // expandForeigns expands all foreign references for an array of Model
-func expandForeigns(modelBuilder BuildModel, models []Model) error {
+func expandForeigns(ctx context.Context, modelBuilder BuildModel, models []Model) error {
 	// Expand all foreign references
 	for _, field := range modelBuilder().GetConfiguration().Fields {
 		// If the field is a foreign key
 		if field.GetReference != nil && field.SetReference != nil {
 			builder, foreignField := field.GetReference()
-			err := expandForeignsByField(field.Name, builder, foreignField, models)
+			err := expandForeignsByField(ctx, field.Name, builder, foreignField, models)
 			if err != nil {
 				return err
 			}
@@ -106,7 +180,10 @@ func expandForeigns(modelBuilder BuildModel, models []Model) error {
 }
 
 // expandForeignsByField expands a single foreign key for an array of Model
-func expandForeignsByField(fieldName string, foreignBuilder BuildModel, foreignField string, models []Model) error {
+//
+// The foreign models are read under the same context, so foreign models that
+// are soft deleted are filtered in the same way as the models themselves.
+func expandForeignsByField(ctx context.Context, fieldName string, foreignBuilder BuildModel, foreignField string, models []Model) error {
 	// Get Foreign IDs
 	ids := make([]interface{}, 0)
 	for _, model := range models {
@@ -135,7 +212,9 @@ func expandForeignsByField(fieldName string, foreignBuilder BuildModel, foreignF
 	}
 
 	// Load Foreign models
-	foreignModels, err := foreignBuilder().BulkFetch(
+	foreignModels, err := bulkFetchContext(
+		ctx,
+		foreignBuilder(),
 		BulkFetchConfig{
 			Limit: len(ids),
 			Predicates: []Predicate{{
@@ -196,13 +275,368 @@ func appendIfMissing(slice []interface{}, i int64) []interface{} {
 	return append(slice, i)
 }
 
+// setInt64 sets an integer value into a field pointer.
+//
+// The pointer may only be an `*int64`, `*int` or `*null.Int`.
+func setInt64(pointer interface{}, value int64) {
+	switch tv := pointer.(type) {
+	case *int64:
+		*tv = value
+		break
+	case *int:
+		*tv = int(value)
+		break
+	case *null.Int:
+		*tv = null.IntFrom(value)
+		break
+	default:
+		panic(fmt.Sprintf("Cannot set an integer into a field of type `%T`", pointer))
+	}
+}
+
+// setLastInsertId sets the LAST_INSERT_ID() of res into the first
+// UniqueIdentifier of config that is not Insertable, which is assumed
+// to be the auto incrementing field
+func setLastInsertId(config *Configuration, res sql.Result) error {
+	lastInsertId, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, field := range config.Fields {
+		if field.UniqueIdentifier && !field.Insertable {
+			if lastInsertId != 0 {
+				setInt64(field.Pointer, lastInsertId)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// insertableUniqueIdentifier returns the index of the first UniqueIdentifier
+// of config that is Insertable and comparable, along with if there is one
+func insertableUniqueIdentifier(config *Configuration) (int, bool) {
+	for i, field := range config.Fields {
+		if field.UniqueIdentifier && field.Insertable && reflect.TypeOf(field.Pointer).Elem().Comparable() {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// versionField returns the first Version field of config, along with
+// if config has a Version field
+func versionField(config *Configuration) (Field, bool) {
+	for _, field := range config.Fields {
+		if field.Version {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// nextVersion returns the value that an update sets a Version field to,
+// which is one more than an integer, or the current time of a timestamp
+//
+// The field may only be an `int64`, `int`, `null.Int`, `time.Time` or `null.Time`.
+func nextVersion(field Field) interface{} {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	switch tv := field.Pointer.(type) {
+	case *int64:
+		return *tv + 1
+	case *int:
+		return *tv + 1
+	case *null.Int:
+		return null.IntFrom(tv.Int64 + 1)
+	case *time.Time:
+		return now
+	case *null.Time:
+		return null.TimeFrom(now)
+	default:
+		panic(fmt.Sprintf("Version field `%v` may only be an integer or a timestamp, not `%T`", field.Name, field.Pointer))
+	}
+}
+
+// integerVersion returns if the Version field is an integer,
+// rather than a timestamp
+func integerVersion(field Field) bool {
+	switch field.Pointer.(type) {
+	case *int64, *int, *null.Int:
+		return true
+	}
+	return false
+}
+
+// fieldNames returns the name of each field
+func fieldNames(fields []Field) []string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// containsField returns if fields has a field named name
+func containsField(fields []Field, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString returns if names has name
+func containsString(names []string, name string) bool {
+	for _, other := range names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePredicates returns an error in the event that any of the
+// predicates filters by a column that isn't a field of config
+//
+// This function will panic in the event that it encounters a malformed predicate
+func validatePredicates(config *Configuration, predicates []Predicate) error {
+	for _, predicate := range predicates {
+		predicate.validate()
+		if predicate.isGroup() {
+			err := validatePredicates(config, predicate.Predicates)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !containsField(config.Fields, predicate.Field) {
+			return invalidColumn("filter", config, predicate.Field)
+		}
+	}
+	return nil
+}
+
+// supportPredicates returns an InvalidOperatorError in the event that any of
+// the predicates, or the predicates of any of its groups, has an operator
+// that dialect doesn't have
+func supportPredicates(dialect Dialect, config *Configuration, predicates []Predicate) error {
+	for _, predicate := range predicates {
+		if predicate.isGroup() {
+			err := supportPredicates(dialect, config, predicate.Predicates)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !dialect.SupportsPredicate(predicate.PredicateType) {
+			return &InvalidOperatorError{TableName: config.TableName, Column: predicate.Field,
+				Operator: getPredicateTypeString(predicate.PredicateType)}
+		}
+	}
+	return nil
+}
+
+// bulkUpdateFields returns the fields of config that are set by values,
+// in the order of config.Fields
+//
+// An error is returned in the event that values is empty, or that any
+// of its keys isn't the name of an Updatable field.
+func bulkUpdateFields(config *Configuration, values map[string]interface{}) ([]Field, error) {
+	if len(values) == 0 {
+		return nil, errors.New("BulkUpdate requires at least one value")
+	}
+
+	var updateFields []Field
+	for _, field := range config.Fields {
+		if _, ok := values[field.Name]; !ok {
+			continue
+		}
+		if !field.Updatable {
+			return nil, fmt.Errorf("Could not update the column '%v' of table '%v', as it is not Updatable",
+				field.Name, config.TableName)
+		}
+		updateFields = append(updateFields, field)
+	}
+	if len(updateFields) != len(values) {
+		for name := range values {
+			if !containsField(updateFields, name) {
+				return nil, invalidColumn("update", config, name)
+			}
+		}
+	}
+	return updateFields, nil
+}
+
 // consumeRow Scans a *sql.Row into our struct
 // that is using this model
 func consumeRow(w Model, row *sql.Row) error {
-	fields := w.GetConfiguration().Fields
+	return consumeRowFields(w, row, w.GetConfiguration().Fields)
+}
+
+// consumeRowFields Scans a *sql.Row of the columns of fields into our struct
+// that is using this model, and records the fields as loaded
+func consumeRowFields(w Model, row *sql.Row, fields []Field) error {
+	var s []interface{}
+	for _, value := range fields {
+		s = append(s, value.Pointer)
+	}
+	err := row.Scan(s...)
+	if err != nil {
+		return err
+	}
+	w.GetConfiguration().setLoaded(fields)
+	return nil
+}
+
+// consumeRows Scans the current row of *sql.Rows into the fields of names of
+// the model, followed by any extra columns, and records the fields as loaded
+func consumeRows(w Model, rows *sql.Rows, names []string, extra ...interface{}) error {
+	config := w.GetConfiguration()
+	fields, err := config.projection(names)
+	if err != nil {
+		return err
+	}
 	var s []interface{}
 	for _, value := range fields {
 		s = append(s, value.Pointer)
 	}
-	return row.Scan(s...)
+	err = rows.Scan(append(s, extra...)...)
+	if err != nil {
+		return err
+	}
+	config.setLoaded(fields)
+	return nil
+}
+
+// setLoaded records the fields as the fields that were read from the database,
+// and merges a snapshot of their values into the snapshot of the fields that
+// were read before
+func (c *Configuration) setLoaded(fields []Field) {
+	c.loaded = make([]string, len(fields))
+	if c.snapshot == nil {
+		c.snapshot = make(map[string]interface{}, len(fields))
+	}
+	for i, field := range fields {
+		c.loaded[i] = field.Name
+		c.snapshot[field.Name] = snapshotValue(field.Pointer)
+	}
+}
+
+// refreshSnapshot records the values of fields that were written to the
+// database into the snapshot, without changing the fields that were loaded
+func (c *Configuration) refreshSnapshot(fields []Field) {
+	if c.snapshot == nil {
+		return
+	}
+	for _, field := range fields {
+		c.snapshot[field.Name] = snapshotValue(field.Pointer)
+	}
+}
+
+// isChanged returns if the value of field has changed since it was last
+// read from the database, which a field that has never been read always has
+func (c *Configuration) isChanged(field Field) bool {
+	snapshot, ok := c.snapshot[field.Name]
+	if !ok {
+		return true
+	}
+	return !reflect.DeepEqual(snapshot, reflect.ValueOf(field.Pointer).Elem().Interface())
+}
+
+// snapshotValue returns a copy of the value that pointer points to, where
+// slices are copied so that changes to their elements are seen as changes
+func snapshotValue(pointer interface{}) interface{} {
+	value := reflect.ValueOf(pointer).Elem()
+	if value.Kind() == reflect.Slice && !value.IsNil() {
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		reflect.Copy(copied, value)
+		return copied.Interface()
+	}
+	return value.Interface()
+}
+
+// projection returns the fields named by names in the order of Fields,
+// or all of the Fields in the event names is empty
+func (c *Configuration) projection(names []string) ([]Field, error) {
+	if len(names) == 0 {
+		return c.Fields, nil
+	}
+	for _, name := range names {
+		if !containsField(c.Fields, name) {
+			return nil, invalidColumn("select", c, name)
+		}
+	}
+	var fields []Field
+	for _, field := range c.Fields {
+		for _, name := range names {
+			if field.Name == name {
+				fields = append(fields, field)
+				break
+			}
+		}
+	}
+	return fields, nil
+}
+
+// scanRows Scans each of the *sql.Rows into the model at the same position,
+// returning an error in the event the number of rows and models differ
+func scanRows(rows *sql.Rows, models []Model) error {
+	i := 0
+	for rows.Next() {
+		if i >= len(models) {
+			return errors.New("More rows were returned than expected")
+		}
+		fields := models[i].GetConfiguration().Fields
+		var s []interface{}
+		for _, value := range fields {
+			s = append(s, value.Pointer)
+		}
+		err := rows.Scan(s...)
+		if err != nil {
+			return err
+		}
+		models[i].GetConfiguration().setLoaded(fields)
+		i++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if i != len(models) {
+		return fmt.Errorf("Expected %v rows to be returned, but got %v", len(models), i)
+	}
+	return nil
+}
+
+// aggregateTarget returns a pointer to a nil pointer of the type of the
+// field named fieldName, which a nullable aggregate can be scanned into
+func aggregateTarget(config *Configuration, fieldName string) interface{} {
+	for _, field := range config.Fields {
+		if field.Name == fieldName {
+			return reflect.New(reflect.TypeOf(field.Pointer)).Interface()
+		}
+	}
+	return new(interface{})
+}
+
+// aggregateValue returns the value that an aggregate was scanned into
+// by an aggregate, or nil in the event that it was NULL
+func aggregateValue(target interface{}) interface{} {
+	switch tv := target.(type) {
+	case *int64:
+		return *tv
+	case *sql.NullFloat64:
+		if tv.Valid {
+			return tv.Float64
+		}
+		return nil
+	}
+	value := reflect.ValueOf(target).Elem()
+	if value.Kind() != reflect.Ptr {
+		return value.Interface()
+	}
+	if value.IsNil() {
+		return nil
+	}
+	return value.Elem().Interface()
 }
@@ -0,0 +1,1320 @@
+package surf_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-carrot/surf"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/guregu/null.v3"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ================================
+// ========== Test Suite ==========
+// ================================
+
+// ModelTestSuite is the suite of tests that every surf.Model passes.  The
+// suite of each model embeds it, and sets models in its SetupTest.
+type ModelTestSuite struct {
+	suite.Suite
+	models modelBuilder
+
+	// distinctFrom is if the model supports WHERE_IS_DISTINCT_FROM
+	distinctFrom bool
+
+	// postgres is if the model supports the predicates that are specific
+	// to PostgreSQL, such as WHERE_ILIKE and WHERE_CONTAINS
+	postgres bool
+}
+
+func (suite *ModelTestSuite) TestUpsert() {
+	// Insert through an upsert
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	err := rigby.Model.(surf.Upserter).Upsert(surf.UpsertConfig{})
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), int64(0), rigby.Id)
+
+	// Update through an upsert
+	rigbyTwo := NewAnimalWith(suite.models)
+	rigbyTwo.Name = "Rigby Two"
+	rigbyTwo.Slug = "rigby"
+	rigbyTwo.Age = 4
+	err = rigbyTwo.Model.(surf.Upserter).Upsert(surf.UpsertConfig{ConflictFields: []string{"slug"}})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), rigby.Id, rigbyTwo.Id)
+	assert.Equal(suite.T(), "Rigby Two", rigbyTwo.Name)
+	assert.Equal(suite.T(), 4, rigbyTwo.Age)
+
+	// Do nothing through an upsert, which loads the existing row
+	rigbyThree := NewAnimalWith(suite.models)
+	rigbyThree.Name = "Rigby Three"
+	rigbyThree.Slug = "rigby"
+	rigbyThree.Age = 5
+	err = rigbyThree.Model.(surf.Upserter).Upsert(surf.UpsertConfig{Action: surf.UPSERT_DO_NOTHING})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), rigby.Id, rigbyThree.Id)
+	assert.Equal(suite.T(), "Rigby Two", rigbyThree.Name)
+	assert.Equal(suite.T(), 4, rigbyThree.Age)
+
+	// Verify the row
+	rigbyVerification := NewAnimalWith(suite.models)
+	rigbyVerification.Id = rigby.Id
+	err = rigbyVerification.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Rigby Two", rigbyVerification.Name)
+
+	// Upsert with an invalid conflict field
+	err = rigby.Model.(surf.Upserter).Upsert(surf.UpsertConfig{ConflictFields: []string{"helloworld"}})
+	assert.NotNil(suite.T(), err)
+
+	// Upsert a toy, which has no default conflict field
+	toy := NewToyWith(suite.models)
+	toy.Name = "tennis ball"
+	toy.OwnerId = rigby.Id
+	err = toy.Model.(surf.Upserter).Upsert(surf.UpsertConfig{})
+	assert.NotNil(suite.T(), err)
+
+	// Clean up
+	rigby.Delete()
+}
+
+func (suite *ModelTestSuite) TestBulkUpdateAndDelete() {
+	// Create some Animals
+	for _, slug := range []string{"luna", "rae", "rigby"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = slug
+		animal.Slug = slug
+		animal.Age = 3
+		animal.Insert()
+	}
+	rPredicates := []surf.Predicate{{Field: "slug", PredicateType: surf.WHERE_LIKE, Values: []interface{}{"r%"}}}
+
+	// Bulk update
+	updated, err := NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkUpdate(rPredicates, map[string]interface{}{"age": 5, "name": "R"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), updated)
+
+	rigby := NewAnimalWith(suite.models)
+	rigby.Slug = "rigby"
+	rigby.Load()
+	assert.Equal(suite.T(), 5, rigby.Age)
+	assert.Equal(suite.T(), "R", rigby.Name)
+	luna := NewAnimalWith(suite.models)
+	luna.Slug = "luna"
+	luna.Load()
+	assert.Equal(suite.T(), 3, luna.Age)
+
+	// Bulk updates must have predicates, and valid Updatable columns
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkUpdate(nil, map[string]interface{}{"age": 5})
+	assert.NotNil(suite.T(), err)
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkUpdate(rPredicates, map[string]interface{}{})
+	assert.NotNil(suite.T(), err)
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkUpdate(rPredicates, map[string]interface{}{"id": 5})
+	assert.NotNil(suite.T(), err)
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkUpdate(rPredicates, map[string]interface{}{"helloworld": 5})
+	assert.NotNil(suite.T(), err)
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkUpdate([]surf.Predicate{{Field: "helloworld", PredicateType: surf.WHERE_IS_NULL}}, map[string]interface{}{"age": 5})
+	assert.NotNil(suite.T(), err)
+
+	// Nothing is updated in the event of a conflict
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkUpdate(rPredicates, map[string]interface{}{"slug": "r"})
+	assert.NotNil(suite.T(), err)
+	err = rigby.Load()
+	assert.Nil(suite.T(), err)
+
+	// Bulk delete
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkDelete(nil)
+	assert.NotNil(suite.T(), err)
+	deleted, err := NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkDelete(rPredicates)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), deleted)
+	err = rigby.Load()
+	assert.NotNil(suite.T(), err)
+	err = luna.Load()
+	assert.Nil(suite.T(), err)
+
+	// Clean up
+	luna.Delete()
+}
+
+func (suite *ModelTestSuite) TestCountAndExists() {
+	// Create some Animals
+	var animals []*Animal
+	for i, slug := range []string{"luna", "rae", "rigby"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = slug
+		animal.Slug = slug
+		animal.Age = i + 1
+		animal.Insert()
+		animals = append(animals, animal)
+	}
+
+	// Count ignores ordering + paging
+	count, err := NewAnimalWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{
+		Limit:    1,
+		Offset:   1,
+		OrderBys: []surf.OrderBy{{Field: "age", Type: surf.ORDER_BY_DESC}},
+		Predicates: []surf.Predicate{
+			{Field: "slug", PredicateType: surf.WHERE_LIKE, Values: []interface{}{"r%"}},
+		},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), count)
+
+	count, err = NewAnimalWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{
+		Predicates: []surf.Predicate{
+			{Field: "age", PredicateType: surf.WHERE_GREATER_THAN, Values: []interface{}{1}},
+			{Field: "slug", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{"rigby"}},
+		},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+
+	// Exists
+	exists, err := NewAnimalWith(suite.models).Model.(surf.Counter).Exists(surf.BulkFetchConfig{
+		Limit: 1,
+		Predicates: []surf.Predicate{
+			{Field: "slug", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{"luna"}},
+		},
+	})
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), exists)
+
+	exists, err = NewAnimalWith(suite.models).Model.(surf.Counter).Exists(surf.BulkFetchConfig{
+		Predicates: []surf.Predicate{
+			{Field: "slug", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{"helloworld"}},
+		},
+	})
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), exists)
+
+	// Clean up
+	for _, animal := range animals {
+		animal.Delete()
+	}
+}
+
+func (suite *ModelTestSuite) TestAggregate() {
+	// Create some Animals
+	var animals []*Animal
+	for i, name := range []string{"dog", "dog", "cat"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = name
+		animal.Slug = "aggregate-" + strconv.Itoa(i)
+		animal.Age = []int{2, 4, 3}[i]
+		animal.Insert()
+		animals = append(animals, animal)
+	}
+	slugPredicates := []surf.Predicate{{Field: "slug", PredicateType: surf.WHERE_LIKE, Values: []interface{}{"aggregate-%"}}}
+
+	// Group by
+	rows, err := NewAnimalWith(suite.models).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		Aggregates: []surf.Aggregate{
+			{Function: surf.AGGREGATE_COUNT},
+			{Function: surf.AGGREGATE_SUM, Field: "age"},
+			{Function: surf.AGGREGATE_AVG, Field: "age"},
+			{Function: surf.AGGREGATE_MIN, Field: "age", Alias: "youngest"},
+			{Function: surf.AGGREGATE_MAX, Field: "age"},
+		},
+		GroupBys:   []string{"name"},
+		Predicates: slugPredicates,
+		OrderBys:   []surf.OrderBy{{Field: "name", Type: surf.ORDER_BY_ASC}},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(rows))
+	if len(rows) == 2 {
+		assert.Equal(suite.T(), map[string]interface{}{"name": "cat"}, rows[0].Groups)
+		assert.Equal(suite.T(), map[string]interface{}{
+			"count": int64(1), "sum_age": 3.0, "avg_age": 3.0, "youngest": 3, "max_age": 3,
+		}, rows[0].Values)
+		assert.Equal(suite.T(), map[string]interface{}{"name": "dog"}, rows[1].Groups)
+		assert.Equal(suite.T(), map[string]interface{}{
+			"count": int64(2), "sum_age": 6.0, "avg_age": 3.0, "youngest": 2, "max_age": 4,
+		}, rows[1].Values)
+	}
+
+	// Having
+	rows, err = NewAnimalWith(suite.models).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		Aggregates: []surf.Aggregate{{Function: surf.AGGREGATE_COUNT}},
+		GroupBys:   []string{"name"},
+		Predicates: slugPredicates,
+		Having:     []surf.Predicate{{Field: "count", PredicateType: surf.WHERE_GREATER_THAN, Values: []interface{}{1}}},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(rows))
+	if len(rows) == 1 {
+		assert.Equal(suite.T(), "dog", rows[0].Groups["name"])
+	}
+
+	// Without a group by
+	rows, err = NewAnimalWith(suite.models).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		Aggregates: []surf.Aggregate{
+			{Function: surf.AGGREGATE_COUNT_DISTINCT, Field: "name"},
+			{Function: surf.AGGREGATE_MAX, Field: "age"},
+		},
+		Predicates: slugPredicates,
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []surf.AggregateRow{{
+		Groups: map[string]interface{}{},
+		Values: map[string]interface{}{"count_distinct_name": int64(2), "max_age": 4},
+	}}, rows)
+
+	// NULL aggregates of no rows
+	rows, err = NewAnimalWith(suite.models).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		Aggregates: []surf.Aggregate{
+			{Function: surf.AGGREGATE_COUNT},
+			{Function: surf.AGGREGATE_SUM, Field: "age"},
+			{Function: surf.AGGREGATE_MIN, Field: "age"},
+		},
+		Predicates: []surf.Predicate{{Field: "slug", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{"helloworld"}}},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []surf.AggregateRow{{
+		Groups: map[string]interface{}{},
+		Values: map[string]interface{}{"count": int64(0), "sum_age": nil, "min_age": nil},
+	}}, rows)
+
+	// Invalid configurations
+	_, err = NewAnimalWith(suite.models).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{})
+	assert.NotNil(suite.T(), err)
+	_, err = NewAnimalWith(suite.models).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		Aggregates: []surf.Aggregate{{Function: surf.AGGREGATE_SUM}},
+	})
+	assert.NotNil(suite.T(), err)
+	_, err = NewAnimalWith(suite.models).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		Aggregates: []surf.Aggregate{{Function: surf.AGGREGATE_COUNT}},
+		GroupBys:   []string{"helloworld"},
+	})
+	assert.NotNil(suite.T(), err)
+	_, err = NewAnimalWith(suite.models).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		Aggregates: []surf.Aggregate{{Function: surf.AGGREGATE_COUNT}},
+		Having:     []surf.Predicate{{Field: "age", PredicateType: surf.WHERE_IS_NULL}},
+	})
+	assert.NotNil(suite.T(), err)
+
+	// Clean up
+	for _, animal := range animals {
+		animal.Delete()
+	}
+}
+
+func (suite *ModelTestSuite) TestKeysetPagination() {
+	// Create some Animals
+	var animals []*Animal
+	for i, age := range []int{3, 1, 3, 2, 3} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = "keyset"
+		animal.Slug = "keyset-" + strconv.Itoa(i)
+		animal.Age = age
+		animal.Insert()
+		animals = append(animals, animal)
+	}
+
+	// fetchAll fetches every page of fetchConfig, returning the slugs in order
+	fetchAll := func(fetchConfig surf.BulkFetchConfig) []string {
+		fetchConfig.Limit = 2
+		fetchConfig.Keyset = true
+		fetchConfig.Predicates = []surf.Predicate{{Field: "slug", PredicateType: surf.WHERE_LIKE, Values: []interface{}{"keyset-%"}}}
+		var slugs []string
+		for page := 0; page < 5; page++ {
+			models, err := NewAnimalWith(suite.models).BulkFetch(fetchConfig, func() surf.Model { return NewAnimalWith(suite.models) })
+			assert.Nil(suite.T(), err)
+			for _, model := range models {
+				slugs = append(slugs, model.(*Animal).Slug)
+			}
+			if len(models) < fetchConfig.Limit {
+				break
+			}
+			fetchConfig.Cursor, err = fetchConfig.NextCursor(models[len(models)-1])
+			assert.Nil(suite.T(), err)
+		}
+		return slugs
+	}
+
+	// The tiebreaker follows the direction of the last OrderBy
+	assert.Equal(suite.T(), []string{"keyset-4", "keyset-2", "keyset-0", "keyset-3", "keyset-1"}, fetchAll(surf.BulkFetchConfig{
+		OrderBys: []surf.OrderBy{{Field: "age", Type: surf.ORDER_BY_DESC}},
+	}))
+
+	// Mixed directions
+	assert.Equal(suite.T(), []string{"keyset-1", "keyset-3", "keyset-4", "keyset-2", "keyset-0"}, fetchAll(surf.BulkFetchConfig{
+		OrderBys: []surf.OrderBy{{Field: "age", Type: surf.ORDER_BY_ASC}, {Field: "slug", Type: surf.ORDER_BY_DESC}},
+	}))
+
+	// Rows inserted before the cursor aren't duplicated into the next page
+	fetchConfig := surf.BulkFetchConfig{
+		Limit:      2,
+		Keyset:     true,
+		OrderBys:   []surf.OrderBy{{Field: "age", Type: surf.ORDER_BY_DESC}},
+		Predicates: []surf.Predicate{{Field: "slug", PredicateType: surf.WHERE_LIKE, Values: []interface{}{"keyset-%"}}},
+	}
+	models, err := NewAnimalWith(suite.models).BulkFetch(fetchConfig, func() surf.Model { return NewAnimalWith(suite.models) })
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(models))
+	fetchConfig.Cursor, err = fetchConfig.NextCursor(models[len(models)-1])
+	assert.Nil(suite.T(), err)
+
+	oldest := NewAnimalWith(suite.models)
+	oldest.Name = "keyset"
+	oldest.Slug = "keyset-5"
+	oldest.Age = 9
+	oldest.Insert()
+	animals = append(animals, oldest)
+
+	models, err = NewAnimalWith(suite.models).BulkFetch(fetchConfig, func() surf.Model { return NewAnimalWith(suite.models) })
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(models))
+	if len(models) == 2 {
+		assert.Equal(suite.T(), "keyset-0", models[0].(*Animal).Slug)
+		assert.Equal(suite.T(), "keyset-3", models[1].(*Animal).Slug)
+	}
+
+	// Cursors can't be used with other OrderBys, or be tampered with
+	fetchConfig.OrderBys = []surf.OrderBy{{Field: "age", Type: surf.ORDER_BY_ASC}}
+	_, err = NewAnimalWith(suite.models).BulkFetch(fetchConfig, func() surf.Model { return NewAnimalWith(suite.models) })
+	assert.NotNil(suite.T(), err)
+	fetchConfig.Cursor = "helloworld"
+	_, err = NewAnimalWith(suite.models).BulkFetch(fetchConfig, func() surf.Model { return NewAnimalWith(suite.models) })
+	assert.NotNil(suite.T(), err)
+
+	// Clean up
+	for _, animal := range animals {
+		animal.Delete()
+	}
+}
+
+func (suite *ModelTestSuite) TestKeysetPaginationNulls() {
+	// Create two Animals, and some toys, half of which have no second owner
+	var animals []*Animal
+	for _, slug := range []string{"keyset-a", "keyset-b"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = "keyset"
+		animal.Slug = slug
+		animal.Insert()
+		animals = append(animals, animal)
+	}
+	positions := make(map[int64]int)
+	for i, secondOwner := range []null.Int{null.IntFrom(animals[0].Id), {}, null.IntFrom(animals[1].Id), {}} {
+		toy := NewToyWith(suite.models)
+		toy.Name = "keyset"
+		toy.OwnerId = animals[0].Id
+		toy.SecondOwnerId = secondOwner
+		toy.Insert()
+		positions[toy.Id] = i
+	}
+
+	// fetchAll fetches every page of one toy with orderBy, returning the
+	// position each toy was inserted at in order
+	fetchAll := func(orderBy surf.OrderBy) []int {
+		fetchConfig := surf.BulkFetchConfig{
+			Limit:      1,
+			Keyset:     true,
+			OrderBys:   []surf.OrderBy{orderBy},
+			Predicates: []surf.Predicate{{Field: "owner", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{animals[0].Id}}},
+		}
+		var order []int
+		for page := 0; page < 5; page++ {
+			models, err := NewToyWith(suite.models).BulkFetch(fetchConfig, func() surf.Model { return NewToyWith(suite.models) })
+			assert.Nil(suite.T(), err)
+			if len(models) == 0 {
+				break
+			}
+			order = append(order, positions[models[0].(*Toy).Id])
+			fetchConfig.Cursor, err = fetchConfig.NextCursor(models[0])
+			assert.Nil(suite.T(), err)
+		}
+		return order
+	}
+
+	// Every toy is paged through, whichever way the NULL values are ordered
+	assert.Equal(suite.T(), []int{0, 2, 1, 3}, fetchAll(surf.OrderBy{Field: "second_owner", Type: surf.ORDER_BY_ASC}))
+	assert.Equal(suite.T(), []int{0, 2, 1, 3}, fetchAll(surf.OrderBy{Field: "second_owner", Type: surf.ORDER_BY_ASC, Nulls: surf.NULLS_LAST}))
+	assert.Equal(suite.T(), []int{1, 3, 0, 2}, fetchAll(surf.OrderBy{Field: "second_owner", Type: surf.ORDER_BY_ASC, Nulls: surf.NULLS_FIRST}))
+	assert.Equal(suite.T(), []int{3, 1, 2, 0}, fetchAll(surf.OrderBy{Field: "second_owner", Type: surf.ORDER_BY_DESC}))
+	assert.Equal(suite.T(), []int{2, 0, 3, 1}, fetchAll(surf.OrderBy{Field: "second_owner", Type: surf.ORDER_BY_DESC, Nulls: surf.NULLS_LAST}))
+
+	// Clean up, which also deletes the toys
+	for _, animal := range animals {
+		animal.Delete()
+	}
+}
+
+func (suite *ModelTestSuite) stream(ctx context.Context, models modelBuilder) {
+	// Create an Animal, with some toys
+	cat := NewAnimalWith(models)
+	cat.Name = "Luna"
+	cat.Slug = "luna"
+	cat.Insert()
+	var toys []*Toy
+	for _, name := range []string{"tennis ball", "sock", "mouse", "feather", "box"} {
+		toy := NewToyWith(models)
+		toy.Name = name
+		toy.OwnerId = cat.Id
+		toy.Insert()
+		toys = append(toys, toy)
+	}
+	fetchConfig := surf.BulkFetchConfig{
+		Limit:    10,
+		OrderBys: []surf.OrderBy{{Field: "id", Type: surf.ORDER_BY_ASC}},
+	}
+	buildToy := func() surf.Model { return NewToyWith(models) }
+
+	// Stream, expanding the owners in batches
+	var names []string
+	err := NewToyWith(models).Model.(surf.Streamer).StreamContext(ctx, fetchConfig, buildToy, surf.StreamConfig{ExpandForeigns: true, BatchSize: 2}, func(model surf.Model) error {
+		toy := model.(*Toy)
+		names = append(names, toy.Name)
+		assert.NotNil(suite.T(), toy.Owner)
+		if toy.Owner != nil {
+			assert.Equal(suite.T(), "Luna", toy.Owner.Name)
+		}
+		return nil
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"tennis ball", "sock", "mouse", "feather", "box"}, names)
+
+	// The Limit holds across the batches
+	names = nil
+	limitConfig := fetchConfig
+	limitConfig.Limit = 3
+	err = NewToyWith(models).Model.(surf.Streamer).StreamContext(ctx, limitConfig, buildToy, surf.StreamConfig{ExpandForeigns: true, BatchSize: 2}, func(model surf.Model) error {
+		names = append(names, model.(*Toy).Name)
+		return nil
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"tennis ball", "sock", "mouse"}, names)
+
+	// Stream without expanding
+	names = nil
+	err = NewToyWith(models).Model.(surf.Streamer).StreamContext(ctx, fetchConfig, buildToy, surf.StreamConfig{}, func(model surf.Model) error {
+		names = append(names, model.(*Toy).Name)
+		assert.Nil(suite.T(), model.(*Toy).Owner)
+		return nil
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 5, len(names))
+
+	// An error from handle stops the stream
+	handled := 0
+	err = NewToyWith(models).Model.(surf.Streamer).StreamContext(ctx, fetchConfig, buildToy, surf.StreamConfig{}, func(model surf.Model) error {
+		handled++
+		return errors.New("stop")
+	})
+	assert.Equal(suite.T(), "stop", err.Error())
+	assert.Equal(suite.T(), 1, handled)
+
+	// Deleting a row that was already handled doesn't skip the rows after it
+	names = nil
+	err = NewToyWith(models).Model.(surf.Streamer).StreamContext(ctx, fetchConfig, buildToy, surf.StreamConfig{ExpandForeigns: true, BatchSize: 2}, func(model surf.Model) error {
+		names = append(names, model.(*Toy).Name)
+		if len(names) == 2 {
+			return toys[0].Delete()
+		}
+		return nil
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"tennis ball", "sock", "mouse", "feather", "box"}, names)
+
+	// Clean up
+	for _, toy := range toys {
+		toy.Delete()
+	}
+	cat.Delete()
+}
+
+func (suite *ModelTestSuite) TestStream() {
+	suite.stream(context.Background(), suite.models)
+}
+
+// streamInTx runs TestStream with the models of builder inside of a
+// transaction on db, where the foreign references are loaded on the
+// connection of the transaction
+func (suite *ModelTestSuite) streamInTx(db *sql.DB, builder func(surf.Executor) modelBuilder) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	txErr := surf.WithTxContext(ctx, db, func(tx *surf.Tx) error {
+		suite.stream(ctx, builder(tx))
+		return nil
+	})
+	assert.Nil(suite.T(), txErr)
+}
+
+func (suite *ModelTestSuite) TestPredicateGroups() {
+	// Create some Animals
+	var animals []*Animal
+	for i, slug := range []string{"bob", "luna", "rae", "rigby"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = slug
+		animal.Slug = slug
+		animal.Age = i + 2
+		animal.Insert()
+		animals = append(animals, animal)
+	}
+
+	// fetchSlugs returns the slugs of the animals that match predicates
+	fetchSlugs := func(predicates ...surf.Predicate) []string {
+		models, err := NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+			Limit:      10,
+			OrderBys:   []surf.OrderBy{{Field: "slug", Type: surf.ORDER_BY_ASC}},
+			Predicates: predicates,
+		}, func() surf.Model {
+			return NewAnimalWith(suite.models)
+		})
+		assert.Nil(suite.T(), err)
+		var slugs []string
+		for _, model := range models {
+			slugs = append(slugs, model.(*Animal).Slug)
+		}
+		return slugs
+	}
+	slugEqual := func(slug string) surf.Predicate {
+		return surf.Predicate{Field: "slug", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{slug}}
+	}
+	rName := surf.Predicate{Field: "name", PredicateType: surf.WHERE_LIKE, Values: []interface{}{"r%"}}
+
+	// Or
+	assert.Equal(suite.T(), []string{"luna", "rae", "rigby"}, fetchSlugs(surf.Or(rName, slugEqual("luna"))))
+
+	// Groups nest, alongside flat predicates
+	assert.Equal(suite.T(), []string{"bob", "rae"}, fetchSlugs(
+		surf.Predicate{Field: "age", PredicateType: surf.WHERE_LESS_THAN_OR_EQUAL_TO, Values: []interface{}{4}},
+		surf.Or(slugEqual("rae"), surf.And(surf.Not(rName), surf.Not(slugEqual("luna")))),
+	))
+	assert.Equal(suite.T(), []string{"rae", "rigby"}, fetchSlugs(surf.Not(surf.Or(slugEqual("luna"), slugEqual("bob")))))
+
+	// Groups work wherever predicates do
+	count, err := NewAnimalWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{
+		Predicates: []surf.Predicate{surf.Or(rName, slugEqual("bob"))},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), count)
+
+	// The fields of a group are validated
+	_, err = NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkDelete([]surf.Predicate{surf.Or(rName, surf.Predicate{Field: "helloworld", PredicateType: surf.WHERE_IS_NULL})})
+	assert.NotNil(suite.T(), err)
+	assert.Panics(suite.T(), func() {
+		NewAnimalWith(suite.models).Model.(surf.BulkWriter).BulkDelete([]surf.Predicate{surf.Or()})
+	})
+
+	// A comparison with NULL is unknown, and so is its Not
+	var toys []*Toy
+	for _, secondOwnerId := range []null.Int{null.IntFrom(animals[1].Id), null.IntFrom(animals[2].Id), {}} {
+		toy := NewToyWith(suite.models)
+		toy.Name = "toy"
+		toy.OwnerId = animals[0].Id
+		toy.SecondOwnerId = secondOwnerId
+		toy.Insert()
+		toys = append(toys, toy)
+	}
+	secondOwnerEqual := surf.Predicate{Field: "second_owner", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{animals[1].Id}}
+	secondOwnerNull := surf.Predicate{Field: "second_owner", PredicateType: surf.WHERE_IS_NULL}
+	for _, test := range []struct {
+		predicate surf.Predicate
+		count     int64
+	}{
+		{surf.Not(secondOwnerEqual), 1},
+		{surf.Not(surf.Not(secondOwnerEqual)), 1},
+		{surf.Or(surf.Not(secondOwnerEqual), secondOwnerNull), 2},
+		{surf.Not(surf.And(secondOwnerEqual, surf.Not(secondOwnerNull))), 2},
+		{surf.Not(surf.And(secondOwnerEqual, secondOwnerNull)), 2},
+	} {
+		count, err := NewToyWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{
+			Predicates: []surf.Predicate{test.predicate},
+		})
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), test.count, count)
+	}
+
+	// Clean up
+	for _, toy := range toys {
+		toy.Delete()
+	}
+	for _, animal := range animals {
+		animal.Delete()
+	}
+}
+
+func (suite *ModelTestSuite) TestPredicateOperators() {
+	// Create some Animals
+	var animals []*Animal
+	for i, slug := range []string{"bob", "luna", "rae", "rigby"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = strings.ToUpper(slug[:1]) + slug[1:]
+		animal.Slug = slug
+		animal.Age = i + 2
+		animal.Insert()
+		animals = append(animals, animal)
+	}
+
+	// fetchSlugs returns the slugs of the animals that match predicate
+	fetchSlugs := func(predicate surf.Predicate) []string {
+		models, err := NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+			Limit:      10,
+			OrderBys:   []surf.OrderBy{{Field: "slug", Type: surf.ORDER_BY_ASC}},
+			Predicates: []surf.Predicate{predicate},
+		}, func() surf.Model {
+			return NewAnimalWith(suite.models)
+		})
+		assert.Nil(suite.T(), err)
+		var slugs []string
+		for _, model := range models {
+			slugs = append(slugs, model.(*Animal).Slug)
+		}
+		return slugs
+	}
+
+	assert.Equal(suite.T(), []string{"luna", "rae"}, fetchSlugs(surf.Predicate{
+		Field: "age", PredicateType: surf.WHERE_BETWEEN, Values: []interface{}{3, 4},
+	}))
+	assert.Equal(suite.T(), []string{"bob", "luna"}, fetchSlugs(surf.Predicate{
+		Field: "name", PredicateType: surf.WHERE_NOT_LIKE, Values: []interface{}{"R%"},
+	}))
+	if suite.distinctFrom {
+		assert.Equal(suite.T(), []string{"bob", "rae", "rigby"}, fetchSlugs(surf.Predicate{
+			Field: "slug", PredicateType: surf.WHERE_IS_DISTINCT_FROM, Values: []interface{}{"luna"},
+		}))
+	}
+
+	// Operators that only PostgreSQL has
+	if suite.postgres {
+		assert.Equal(suite.T(), []string{"rae", "rigby"}, fetchSlugs(surf.Predicate{
+			Field: "name", PredicateType: surf.WHERE_ILIKE, Values: []interface{}{"r%"},
+		}))
+		assert.Equal(suite.T(), []string{"luna", "rae"}, fetchSlugs(surf.Predicate{
+			Field: "name", PredicateType: surf.WHERE_REGEX, Values: []interface{}{"^[LR]a?[eu]"},
+		}))
+		assert.Equal(suite.T(), []string{"rae"}, fetchSlugs(surf.Predicate{
+			Field: "name", PredicateType: surf.WHERE_IREGEX, Values: []interface{}{"^RA"},
+		}))
+		assert.Equal(suite.T(), []string{"bob", "rigby"}, fetchSlugs(surf.Predicate{
+			Field: "id", PredicateType: surf.WHERE_ANY, Values: []interface{}{pq.Array([]int64{animals[0].Id, animals[3].Id})},
+		}))
+
+		// Create a Document
+		document := NewDocumentWith(suite.models)
+		document.Tags = pq.StringArray{"a", "b"}
+		document.Data = `{"kind": "cat", "size": 3}`
+		err := document.Insert()
+		assert.Nil(suite.T(), err)
+
+		// countDocuments returns the number of documents that match predicate
+		countDocuments := func(predicate surf.Predicate) int64 {
+			count, err := NewDocumentWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{
+				Predicates: []surf.Predicate{predicate},
+			})
+			assert.Nil(suite.T(), err)
+			return count
+		}
+
+		// Arrays
+		assert.Equal(suite.T(), int64(1), countDocuments(surf.Predicate{
+			Field: "tags", PredicateType: surf.WHERE_CONTAINS, Values: []interface{}{pq.Array([]string{"a"})},
+		}))
+		assert.Equal(suite.T(), int64(0), countDocuments(surf.Predicate{
+			Field: "tags", PredicateType: surf.WHERE_CONTAINS, Values: []interface{}{pq.Array([]string{"a", "c"})},
+		}))
+		assert.Equal(suite.T(), int64(1), countDocuments(surf.Predicate{
+			Field: "tags", PredicateType: surf.WHERE_CONTAINED_BY, Values: []interface{}{pq.Array([]string{"a", "b", "c"})},
+		}))
+		assert.Equal(suite.T(), int64(1), countDocuments(surf.Predicate{
+			Field: "tags", PredicateType: surf.WHERE_OVERLAPS, Values: []interface{}{pq.Array([]string{"b", "z"})},
+		}))
+		assert.Equal(suite.T(), int64(0), countDocuments(surf.Predicate{
+			Field: "tags", PredicateType: surf.WHERE_OVERLAPS, Values: []interface{}{pq.Array([]string{"z"})},
+		}))
+
+		// JSON
+		assert.Equal(suite.T(), int64(1), countDocuments(surf.Predicate{
+			Field: "data", PredicateType: surf.WHERE_CONTAINS, Values: []interface{}{`{"kind": "cat"}`},
+		}))
+		assert.Equal(suite.T(), int64(0), countDocuments(surf.Predicate{
+			Field: "data", PredicateType: surf.WHERE_CONTAINS, Values: []interface{}{`{"kind": "dog"}`},
+		}))
+		assert.Equal(suite.T(), int64(1), countDocuments(surf.Predicate{
+			Field: "data", PredicateType: surf.WHERE_HAS_KEY, Values: []interface{}{"size"},
+		}))
+		assert.Equal(suite.T(), int64(0), countDocuments(surf.Predicate{
+			Field: "data", PredicateType: surf.WHERE_HAS_KEY, Values: []interface{}{"color"},
+		}))
+		assert.Equal(suite.T(), int64(1), countDocuments(surf.Predicate{
+			Field: "data", PredicateType: surf.WHERE_JSON_TEXT_EQUAL, Values: []interface{}{"size", "3"},
+		}))
+		document.Delete()
+	} else {
+		// Operators that the database doesn't have are an error in every
+		// query, including inside of a group
+		var invalidOperatorErr *surf.InvalidOperatorError
+		ilike := surf.Predicate{Field: "name", PredicateType: surf.WHERE_ILIKE, Values: []interface{}{"r%"}}
+		grouped := surf.Or(surf.Predicate{Field: "slug", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{"bob"}}, surf.Not(ilike))
+		model := NewAnimalWith(suite.models).Model
+		buildAnimal := func() surf.Model { return NewAnimalWith(suite.models) }
+
+		_, err := model.BulkFetch(surf.BulkFetchConfig{Limit: 10, Predicates: []surf.Predicate{ilike}}, buildAnimal)
+		assert.True(suite.T(), errors.As(err, &invalidOperatorErr))
+		_, err = model.(surf.Counter).Count(surf.BulkFetchConfig{Predicates: []surf.Predicate{grouped}})
+		assert.True(suite.T(), errors.As(err, &invalidOperatorErr))
+		err = model.(surf.Streamer).Stream(surf.BulkFetchConfig{Limit: 10, Predicates: []surf.Predicate{grouped}}, buildAnimal, surf.StreamConfig{}, func(surf.Model) error {
+			return nil
+		})
+		assert.True(suite.T(), errors.As(err, &invalidOperatorErr))
+		_, err = model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+			Aggregates: []surf.Aggregate{{Function: surf.AGGREGATE_COUNT}},
+			Predicates: []surf.Predicate{grouped},
+		})
+		assert.True(suite.T(), errors.As(err, &invalidOperatorErr))
+		_, err = model.(surf.BulkWriter).BulkUpdate([]surf.Predicate{ilike}, map[string]interface{}{"age": 9})
+		assert.True(suite.T(), errors.As(err, &invalidOperatorErr))
+		_, err = model.(surf.BulkWriter).BulkDelete([]surf.Predicate{grouped})
+		assert.True(suite.T(), errors.As(err, &invalidOperatorErr))
+		assert.Equal(suite.T(), []string{"bob", "luna", "rae", "rigby"}, fetchSlugs(surf.Predicate{
+			Field: "age", PredicateType: surf.WHERE_GREATER_THAN, Values: []interface{}{0},
+		}))
+	}
+
+	// Arity is validated
+	assert.Panics(suite.T(), func() {
+		fetchSlugs(surf.Predicate{Field: "age", PredicateType: surf.WHERE_BETWEEN, Values: []interface{}{3}})
+	})
+	assert.Panics(suite.T(), func() {
+		fetchSlugs(surf.Predicate{Field: "name", PredicateType: surf.WHERE_ILIKE})
+	})
+
+	// Clean up
+	for _, animal := range animals {
+		animal.Delete()
+	}
+}
+
+func (suite *ModelTestSuite) TestFilterQuery() {
+	// Create some Animals
+	var animals []*Animal
+	for i, slug := range []string{"bob", "luna", "rae", "rigby"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = strings.ToUpper(slug[:1]) + slug[1:]
+		animal.Slug = slug
+		animal.Age = i + 2
+		animal.Insert()
+		animals = append(animals, animal)
+	}
+
+	// filterSlugs returns the slugs of the animals that match the filters of query
+	filterSlugs := func(query string) []string {
+		values, err := url.ParseQuery(query)
+		assert.Nil(suite.T(), err)
+		config := surf.BulkFetchConfig{
+			Limit:    10,
+			OrderBys: []surf.OrderBy{{Field: "slug", Type: surf.ORDER_BY_ASC}},
+		}
+		err = config.ConsumeFilterQuery(values, NewAnimalWith(suite.models))
+		assert.Nil(suite.T(), err)
+		models, err := NewAnimalWith(suite.models).BulkFetch(config, func() surf.Model {
+			return NewAnimalWith(suite.models)
+		})
+		assert.Nil(suite.T(), err)
+		var slugs []string
+		for _, model := range models {
+			slugs = append(slugs, model.(*Animal).Slug)
+		}
+		return slugs
+	}
+
+	assert.Equal(suite.T(), []string{"rae", "rigby"}, filterSlugs("filter[age][gte]=4"))
+	assert.Equal(suite.T(), []string{"rae", "rigby"}, filterSlugs("filter[name][like]=R%25"))
+	assert.Equal(suite.T(), []string{"rae"}, filterSlugs("filter[name][like]=R%25&filter[age][lt]=5"))
+	assert.Equal(suite.T(), []string{"luna"}, filterSlugs("filter[name]=Luna"))
+	assert.Equal(suite.T(), []string{"bob", "rigby"}, filterSlugs("filter[age][in]=2,5"))
+	assert.Equal(suite.T(), []string{"luna", "rae"}, filterSlugs("filter[age][between]=3,4"))
+	assert.Equal(suite.T(), []string{"bob", "luna"}, filterSlugs("filter[age][ne]=4&filter[age][ne]=5"))
+	assert.Equal(suite.T(), []string{"bob", "luna", "rae", "rigby"}, filterSlugs("sort=-age"))
+
+	// Filter by a null.Int
+	toy := NewToyWith(suite.models)
+	toy.Name = "Ball"
+	toy.OwnerId = animals[0].Id
+	toy.SecondOwnerId = null.IntFrom(animals[1].Id)
+	toy.Insert()
+	toy = NewToyWith(suite.models)
+	toy.Name = "Bone"
+	toy.OwnerId = animals[0].Id
+	toy.Insert()
+	for query, name := range map[string]string{
+		"filter[second_owner][null]=true":                              "Bone",
+		"filter[second_owner][null]=false":                             "Ball",
+		"filter[second_owner]=" + strconv.FormatInt(animals[1].Id, 10): "Ball",
+	} {
+		values, _ := url.ParseQuery(query)
+		config := surf.BulkFetchConfig{Limit: 10}
+		err := config.ConsumeFilterQuery(values, NewToyWith(suite.models))
+		assert.Nil(suite.T(), err)
+		models, err := NewToyWith(suite.models).BulkFetch(config, func() surf.Model {
+			return NewToyWith(suite.models)
+		})
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), 1, len(models))
+		assert.Equal(suite.T(), name, models[0].(*Toy).Name)
+	}
+
+}
+
+func (suite *ModelTestSuite) TestOrderByNullsAndExpressions() {
+	// Create some Animals
+	var animals []*Animal
+	for i, name := range []string{"bob", "Luna", "Rae", "rigby"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = name
+		animal.Slug = strings.ToLower(name)
+		animal.Age = i + 2
+		animal.Insert()
+		animals = append(animals, animal)
+	}
+
+	// Create some Toys, where only some have a second owner
+	for i, name := range []string{"a", "b", "c", "d"} {
+		toy := NewToyWith(suite.models)
+		toy.Name = name
+		toy.OwnerId = animals[0].Id
+		if i%2 == 1 {
+			toy.SecondOwnerId = null.IntFrom(animals[i].Id)
+		}
+		toy.Insert()
+	}
+
+	// sortToyNames returns the names of the toys, in the order of sort
+	sortToyNames := func(sort string) []string {
+		config := surf.BulkFetchConfig{Limit: 10}
+		config.ConsumeSortQuery(sort)
+		models, err := NewToyWith(suite.models).BulkFetch(config, func() surf.Model {
+			return NewToyWith(suite.models)
+		})
+		assert.Nil(suite.T(), err)
+		var names []string
+		for _, model := range models {
+			names = append(names, model.(*Toy).Name)
+		}
+		return names
+	}
+
+	assert.Equal(suite.T(), []string{"a", "c", "b", "d"}, sortToyNames("second_owner:nulls_first,name"))
+	assert.Equal(suite.T(), []string{"a", "c", "d", "b"}, sortToyNames("-second_owner:nulls_first,name"))
+	assert.Equal(suite.T(), []string{"b", "d", "a", "c"}, sortToyNames("second_owner:nulls_last,name"))
+	assert.Equal(suite.T(), []string{"d", "b", "a", "c"}, sortToyNames("-second_owner:nulls_last,name"))
+
+	// Order by an expression, which a surf.MemoryModel can't evaluate
+	animal := NewAnimalWith(suite.models)
+	animal.GetConfiguration().OrderExpressions = map[string]string{"lower_name": "lower(name)"}
+	models, err := animal.BulkFetch(surf.BulkFetchConfig{
+		Limit:    10,
+		OrderBys: []surf.OrderBy{{Field: "lower_name", Type: surf.ORDER_BY_ASC}},
+	}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	if _, ok := animal.Model.(*surf.MemoryModel); ok {
+		assert.NotNil(suite.T(), err)
+	} else {
+		assert.Nil(suite.T(), err)
+		var names []string
+		for _, model := range models {
+			names = append(names, model.(*Animal).Name)
+		}
+		assert.Equal(suite.T(), []string{"bob", "Luna", "Rae", "rigby"}, names)
+	}
+
+	// Expressions can be sorted by, but not keyset paginated
+	config, err := surf.NewBulkFetchConfig(url.Values{"sort": {"-lower_name"}}, animal)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []surf.OrderBy{{Field: "lower_name", Type: surf.ORDER_BY_DESC}}, config.OrderBys)
+	config.Keyset = true
+	_, err = animal.BulkFetch(config, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ModelTestSuite) TestSparseFieldsets() {
+	// Create an Animal + Toy
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	err := rigby.Insert()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"id", "slug", "name", "age"}, rigby.GetConfiguration().LoadedFields())
+	toy := NewToyWith(suite.models)
+	toy.Name = "Ball"
+	toy.OwnerId = rigby.Id
+	err = toy.Insert()
+	assert.Nil(suite.T(), err)
+
+	// Load some of the fields
+	animal := NewAnimalWith(suite.models)
+	animal.Id = rigby.Id
+	assert.Nil(suite.T(), animal.GetConfiguration().LoadedFields())
+	err = animal.Model.(surf.FieldLoader).LoadFields([]string{"name"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Rigby", animal.Name)
+	assert.Equal(suite.T(), 0, animal.Age)
+	assert.Equal(suite.T(), []string{"name"}, animal.GetConfiguration().LoadedFields())
+	assert.True(suite.T(), animal.GetConfiguration().IsLoaded("name"))
+	assert.False(suite.T(), animal.GetConfiguration().IsLoaded("age"))
+	err = animal.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, animal.Age)
+	assert.True(suite.T(), animal.GetConfiguration().IsLoaded("age"))
+
+	// Fetch some of the fields, in the order of the configuration
+	query, _ := url.ParseQuery("fields=name,id")
+	config, err := surf.NewBulkFetchConfig(query, NewAnimalWith(suite.models))
+	assert.Nil(suite.T(), err)
+	models, err := NewAnimalWith(suite.models).BulkFetch(config, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(models))
+	assert.Equal(suite.T(), rigby.Id, models[0].(*Animal).Id)
+	assert.Equal(suite.T(), "Rigby", models[0].(*Animal).Name)
+	assert.Equal(suite.T(), "", models[0].(*Animal).Slug)
+	assert.Equal(suite.T(), []string{"id", "name"}, models[0].GetConfiguration().LoadedFields())
+
+	// Foreign references that aren't selected aren't expanded
+	models, err = NewToyWith(suite.models).BulkFetch(surf.BulkFetchConfig{Limit: 10, Fields: []string{"name"}}, func() surf.Model {
+		return NewToyWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(models))
+	assert.Equal(suite.T(), "Ball", models[0].(*Toy).Name)
+	assert.Nil(suite.T(), models[0].(*Toy).Owner)
+
+	// Keyset pagination requires the ordered fields to be selected
+	_, err = NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{Limit: 10, Keyset: true, Fields: []string{"name"}}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.NotNil(suite.T(), err)
+	models, err = NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{Limit: 10, Keyset: true, Fields: []string{"id", "name"}}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(models))
+
+	// Invalid fields
+	var invalidColumnErr *surf.InvalidColumnError
+	err = animal.Model.(surf.FieldLoader).LoadFields([]string{"helloworld"})
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	assert.Equal(suite.T(), "select", invalidColumnErr.Action)
+	_, err = NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{Limit: 10, Fields: []string{"helloworld"}}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	_, err = surf.NewBulkFetchConfig(url.Values{"fields": {"name,helloworld"}}, NewAnimalWith(suite.models))
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+}
+
+func (suite *ModelTestSuite) TestDirtyTracking() {
+	// Every field of a new Animal is changed
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	assert.Equal(suite.T(), []string{"id", "slug", "name", "age"}, rigby.GetConfiguration().Changed())
+	err := rigby.Insert()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), rigby.GetConfiguration().Changed())
+
+	// Two copies change different fields
+	other := NewAnimalWith(suite.models)
+	other.Id = rigby.Id
+	err = other.Load()
+	assert.Nil(suite.T(), err)
+	other.Name = "Rigbone"
+	assert.Equal(suite.T(), []string{"name"}, other.GetConfiguration().Changed())
+	err = other.Update()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), other.GetConfiguration().Changed())
+
+	rigby.Age = 4
+	assert.Equal(suite.T(), []string{"age"}, rigby.GetConfiguration().Changed())
+	err = rigby.Update()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Rigbone", rigby.Name)
+
+	// Neither update overwrote the other
+	verification := NewAnimalWith(suite.models)
+	verification.Id = rigby.Id
+	err = verification.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Rigbone", verification.Name)
+	assert.Equal(suite.T(), 4, verification.Age)
+
+	// A partial load keeps the snapshot of the fields it didn't select, so
+	// a concurrent change of them isn't overwritten
+	stale := NewAnimalWith(suite.models)
+	stale.Id = rigby.Id
+	err = stale.Load()
+	assert.Nil(suite.T(), err)
+	verification.Name = "Luna"
+	err = verification.Update()
+	assert.Nil(suite.T(), err)
+	err = stale.Model.(surf.FieldLoader).LoadFields([]string{"age"})
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), stale.GetConfiguration().Changed())
+	stale.Age = 5
+	assert.Equal(suite.T(), []string{"age"}, stale.GetConfiguration().Changed())
+	err = stale.Update()
+	assert.Nil(suite.T(), err)
+	err = verification.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Luna", verification.Name)
+	assert.Equal(suite.T(), 5, verification.Age)
+
+	// Fields that were never loaded are always changed
+	partial := NewAnimalWith(suite.models)
+	partial.Id = rigby.Id
+	err = partial.Model.(surf.FieldLoader).LoadFields([]string{"id", "name"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"slug", "age"}, partial.GetConfiguration().Changed())
+	partial.Slug = "rigbone"
+	partial.Age = 6
+	err = partial.Update()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), partial.GetConfiguration().Changed())
+	err = verification.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Luna", verification.Name)
+	assert.Equal(suite.T(), "rigbone", verification.Slug)
+	assert.Equal(suite.T(), 6, verification.Age)
+
+	// An update without changes is skipped
+	err = verification.Delete()
+	assert.Nil(suite.T(), err)
+	err = rigby.Update()
+	assert.Nil(suite.T(), err)
+	rigby.Age = 5
+	err = rigby.Update()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ModelTestSuite) TestOptimisticLocking() {
+	// Create a Note, and load a second copy of it
+	note := NewNoteWith(suite.models)
+	note.Body = "Hello"
+	err := note.Insert()
+	assert.Nil(suite.T(), err)
+	version := note.Version
+	other := NewNoteWith(suite.models)
+	other.Id = note.Id
+	err = other.Load()
+	assert.Nil(suite.T(), err)
+
+	// Updates increment the version
+	note.Body = "Hello, world"
+	err = note.Update()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), version+1, note.Version)
+
+	// The stale copy can't be updated or deleted
+	other.Body = "Goodbye"
+	err = other.Update()
+	assert.Equal(suite.T(), surf.ErrStaleObject, err)
+	err = other.Delete()
+	assert.Equal(suite.T(), surf.ErrStaleObject, err)
+
+	// Until it is loaded again
+	err = other.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Hello, world", other.Body)
+	other.Body = "Goodbye"
+	err = other.Update()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), version+2, other.Version)
+
+	// A version that is set on the model is checked
+	note.Version = version + 1
+	note.Body = "Hi"
+	err = note.Update()
+	assert.Equal(suite.T(), surf.ErrStaleObject, err)
+	note.Version = version + 2
+	err = note.Update()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Hi", note.Body)
+	assert.Equal(suite.T(), version+3, note.Version)
+
+	// A BulkUpdate increments the version, so a model read before it is stale
+	count, err := NewNoteWith(suite.models).Model.(surf.BulkWriter).BulkUpdate([]surf.Predicate{
+		{Field: "id", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{note.Id}},
+	}, map[string]interface{}{"body": "Hey"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+	note.Body = "Hello again"
+	err = note.Update()
+	assert.Equal(suite.T(), surf.ErrStaleObject, err)
+	err = note.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Hey", note.Body)
+	assert.Equal(suite.T(), version+4, note.Version)
+
+	// Deletes check the version
+	err = other.Delete()
+	assert.Equal(suite.T(), surf.ErrStaleObject, err)
+	err = note.Delete()
+	assert.Nil(suite.T(), err)
+	err = note.Delete()
+	assert.Equal(suite.T(), surf.ErrStaleObject, err)
+
+	// An upsert starts a new row at the first version, and increments the
+	// version of a row that it updates, so a model read before it is stale
+	greeting := NewNoteWith(suite.models)
+	greeting.Slug = null.StringFrom("greeting")
+	greeting.Body = "Hello"
+	err = greeting.Model.(surf.Upserter).Upsert(surf.UpsertConfig{ConflictFields: []string{"slug"}})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), greeting.Version)
+
+	upserted := NewNoteWith(suite.models)
+	upserted.Slug = null.StringFrom("greeting")
+	upserted.Body = "Hi"
+	err = upserted.Model.(surf.Upserter).Upsert(surf.UpsertConfig{ConflictFields: []string{"slug"}})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), greeting.Id, upserted.Id)
+	assert.Equal(suite.T(), int64(2), upserted.Version)
+
+	greeting.Body = "Goodbye"
+	err = greeting.Update()
+	assert.Equal(suite.T(), surf.ErrStaleObject, err)
+	err = upserted.Delete()
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *ModelTestSuite) TestSoftDelete() {
+	// Create a Comment + a reply to it
+	comment := NewCommentWith(suite.models)
+	comment.Body = "Hello"
+	err := comment.Insert()
+	assert.Nil(suite.T(), err)
+	reply := NewCommentWith(suite.models)
+	reply.Body = "Hi"
+	reply.ReplyToId = null.IntFrom(comment.Id)
+	err = reply.Insert()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), comment.Id, reply.ReplyTo.Id)
+
+	// Soft delete the Comment
+	err = comment.Delete()
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), comment.DeletedAt.Valid)
+	assert.Nil(suite.T(), comment.GetConfiguration().Changed())
+	err = comment.Delete()
+	assert.NotNil(suite.T(), err)
+
+	// Reads exclude the Comment, along with the reference to it
+	loaded := NewCommentWith(suite.models)
+	loaded.Id = comment.Id
+	err = loaded.Load()
+	assert.Equal(suite.T(), sql.ErrNoRows, err)
+	loaded.Id = reply.Id
+	err = loaded.Load()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), loaded.ReplyTo)
+
+	fetch := func(ctx context.Context) []surf.Model {
+		models, err := NewCommentWith(suite.models).Model.(surf.ContextModel).BulkFetchContext(ctx, surf.BulkFetchConfig{
+			Limit:    10,
+			OrderBys: []surf.OrderBy{{Field: "id", Type: surf.ORDER_BY_ASC}},
+		}, func() surf.Model {
+			return NewCommentWith(suite.models)
+		})
+		assert.Nil(suite.T(), err)
+		return models
+	}
+	models := fetch(context.Background())
+	assert.Equal(suite.T(), 1, len(models))
+	assert.Equal(suite.T(), reply.Id, models[0].(*Comment).Id)
+	assert.Nil(suite.T(), models[0].(*Comment).ReplyTo)
+	count, err := NewCommentWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+
+	// Unless the Comment is asked for
+	models = fetch(surf.WithDeleted(context.Background()))
+	assert.Equal(suite.T(), 2, len(models))
+	assert.Equal(suite.T(), comment.Id, models[1].(*Comment).ReplyTo.Id)
+	models = fetch(surf.OnlyDeleted(context.Background()))
+	assert.Equal(suite.T(), 1, len(models))
+	assert.Equal(suite.T(), comment.Id, models[0].(*Comment).Id)
+	assert.True(suite.T(), models[0].(*Comment).DeletedAt.Valid)
+	count, err = NewCommentWith(suite.models).Model.(surf.Counter).CountContext(surf.OnlyDeleted(context.Background()), surf.BulkFetchConfig{})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+	loaded.Id = comment.Id
+	err = loaded.Model.(surf.ContextModel).LoadContext(surf.WithDeleted(context.Background()))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Hello", loaded.Body)
+
+	// Restore the Comment
+	err = comment.Model.(surf.SoftDeleter).Restore()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), comment.GetConfiguration().Changed())
+	assert.False(suite.T(), comment.DeletedAt.Valid)
+	err = comment.Model.(surf.SoftDeleter).Restore()
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(fetch(context.Background())))
+	loaded.Id = reply.Id
+	err = loaded.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), comment.Id, loaded.ReplyTo.Id)
+
+	// Hard delete the Comment
+	err = comment.Model.(surf.SoftDeleter).HardDelete()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(fetch(surf.WithDeleted(context.Background()))))
+
+	// Bulk writes soft delete, and only write the rows in the scope of
+	// their context
+	replyId := []surf.Predicate{{Field: "id", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{reply.Id}}}
+	comments := NewCommentWith(suite.models).Model
+	count, err = comments.(surf.BulkWriter).BulkDelete(replyId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+	assert.Equal(suite.T(), 0, len(fetch(context.Background())))
+	count, err = comments.(surf.BulkWriter).BulkDeleteContext(surf.WithDeleted(context.Background()), replyId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), count)
+	count, err = comments.(surf.BulkWriter).BulkUpdate(replyId, map[string]interface{}{"body": "Hey"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), count)
+	count, err = comments.(surf.BulkWriter).BulkUpdateContext(surf.OnlyDeleted(context.Background()), replyId, map[string]interface{}{"body": "Hey"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+	models = fetch(surf.OnlyDeleted(context.Background()))
+	assert.Equal(suite.T(), 1, len(models))
+	assert.Equal(suite.T(), "Hey", models[0].(*Comment).Body)
+	count, err = comments.(surf.SoftDeleter).BulkHardDelete(replyId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), count)
+	count, err = comments.(surf.SoftDeleter).BulkHardDeleteContext(surf.OnlyDeleted(context.Background()), replyId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+	assert.Equal(suite.T(), 0, len(fetch(surf.WithDeleted(context.Background()))))
+
+	// Models without a DeletedAtField can't be restored
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	err = rigby.Insert()
+	assert.Nil(suite.T(), err)
+	err = rigby.Model.(surf.SoftDeleter).Restore()
+	assert.NotNil(suite.T(), err)
+	err = rigby.Delete()
+	assert.Nil(suite.T(), err)
+	err = rigby.Model.(surf.ContextModel).LoadContext(surf.WithDeleted(context.Background()))
+	assert.Equal(suite.T(), sql.ErrNoRows, err)
+}
@@ -0,0 +1,230 @@
+package surf
+
+import (
+	"context"
+)
+
+// MySQLModel is a github.com/go-sql-driver/mysql implementation of a Model
+//
+// As MySQL has no RETURNING clause, rows are re-selected after they are
+// inserted or updated.  On Insert, the first UniqueIdentifier that is not
+// Insertable is assumed to be the AUTO_INCREMENT column, and is set to the
+// LAST_INSERT_ID() of the insert before the re-selection.
+type MySQLModel struct {
+	Database Executor      `json:"-"`
+	Config   Configuration `json:"-"`
+}
+
+// MySQLModel implements every optional interface of a Model
+var _ fullModel = (*MySQLModel)(nil)
+
+// engine returns the sqlEngine that runs the queries for the model
+func (w *MySQLModel) engine() sqlEngine {
+	return sqlEngine{Database: w.Database, Dialect: MySQLDialect{}}
+}
+
+// GetConfiguration returns the configuration for the model
+func (w *MySQLModel) GetConfiguration() *Configuration {
+	return &w.Config
+}
+
+// Insert inserts the model into the database
+func (w *MySQLModel) Insert() error {
+	return w.InsertContext(context.Background())
+}
+
+// InsertContext inserts the model into the database
+func (w *MySQLModel) InsertContext(ctx context.Context) error {
+	return w.engine().insert(ctx, w)
+}
+
+// BulkInsert inserts an array of models into the database
+func (w *MySQLModel) BulkInsert(models []Model) error {
+	return w.BulkInsertContext(context.Background(), models)
+}
+
+// BulkInsertContext inserts an array of models into the database
+func (w *MySQLModel) BulkInsertContext(ctx context.Context, models []Model) error {
+	return w.engine().bulkInsert(ctx, w, models)
+}
+
+// Upsert inserts the model into the database, or resolves a
+// conflict with an existing row
+func (w *MySQLModel) Upsert(upsertConfig UpsertConfig) error {
+	return w.UpsertContext(context.Background(), upsertConfig)
+}
+
+// UpsertContext inserts the model into the database, or resolves a
+// conflict with an existing row
+func (w *MySQLModel) UpsertContext(ctx context.Context, upsertConfig UpsertConfig) error {
+	return w.engine().upsert(ctx, w, upsertConfig)
+}
+
+// Load loads the model from the database from its unique identifier
+// and then loads those values into the struct
+func (w *MySQLModel) Load() error {
+	return w.LoadContext(context.Background())
+}
+
+// LoadContext loads the model from the database from its unique identifier
+// and then loads those values into the struct
+func (w *MySQLModel) LoadContext(ctx context.Context) error {
+	return w.engine().load(ctx, w, nil)
+}
+
+// LoadFields loads the fields of names of the model from the database
+// from its unique identifier, leaving its other fields untouched
+func (w *MySQLModel) LoadFields(names []string) error {
+	return w.LoadFieldsContext(context.Background(), names)
+}
+
+// LoadFieldsContext loads the fields of names of the model from the database
+// from its unique identifier, leaving its other fields untouched
+func (w *MySQLModel) LoadFieldsContext(ctx context.Context, names []string) error {
+	return w.engine().load(ctx, w, names)
+}
+
+// Update updates the model with the current values in the struct
+func (w *MySQLModel) Update() error {
+	return w.UpdateContext(context.Background())
+}
+
+// UpdateContext updates the model with the current values in the struct
+func (w *MySQLModel) UpdateContext(ctx context.Context) error {
+	return w.engine().update(ctx, w)
+}
+
+// BulkUpdate sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *MySQLModel) BulkUpdate(predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.BulkUpdateContext(context.Background(), predicates, values)
+}
+
+// BulkUpdateContext sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *MySQLModel) BulkUpdateContext(ctx context.Context, predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.engine().bulkUpdate(ctx, w, predicates, values)
+}
+
+// Delete deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *MySQLModel) Delete() error {
+	return w.DeleteContext(context.Background())
+}
+
+// DeleteContext deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *MySQLModel) DeleteContext(ctx context.Context) error {
+	return w.engine().delete(ctx, w)
+}
+
+// HardDelete deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *MySQLModel) HardDelete() error {
+	return w.HardDeleteContext(context.Background())
+}
+
+// HardDeleteContext deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *MySQLModel) HardDeleteContext(ctx context.Context) error {
+	return w.engine().hardDelete(ctx, w)
+}
+
+// Restore undoes the soft delete of the model
+func (w *MySQLModel) Restore() error {
+	return w.RestoreContext(context.Background())
+}
+
+// RestoreContext undoes the soft delete of the model
+func (w *MySQLModel) RestoreContext(ctx context.Context) error {
+	return w.engine().restore(ctx, w)
+}
+
+// BulkDelete deletes all rows that match predicates, or soft deletes them
+// in the event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *MySQLModel) BulkDelete(predicates []Predicate) (int64, error) {
+	return w.BulkDeleteContext(context.Background(), predicates)
+}
+
+// BulkDeleteContext deletes all rows that match predicates, or soft deletes
+// them in the event that it has a DeletedAtField, returning the number of
+// rows that were deleted
+func (w *MySQLModel) BulkDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	return w.engine().bulkDelete(ctx, w, predicates)
+}
+
+// BulkHardDelete deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *MySQLModel) BulkHardDelete(predicates []Predicate) (int64, error) {
+	return w.BulkHardDeleteContext(context.Background(), predicates)
+}
+
+// BulkHardDeleteContext deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows that
+// were deleted
+func (w *MySQLModel) BulkHardDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	return w.engine().bulkHardDelete(ctx, w, predicates)
+}
+
+// BulkFetch gets an array of models
+func (w *MySQLModel) BulkFetch(fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	return w.BulkFetchContext(context.Background(), fetchConfig, buildModel)
+}
+
+// BulkFetchContext gets an array of models
+func (w *MySQLModel) BulkFetchContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	return w.engine().bulkFetch(ctx, w, fetchConfig, buildModel)
+}
+
+// Stream calls handle with each of the models of a bulk fetch,
+// as the rows are read from the database
+func (w *MySQLModel) Stream(fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.StreamContext(context.Background(), fetchConfig, buildModel, streamConfig, handle)
+}
+
+// StreamContext calls handle with each of the models of a bulk fetch,
+// as the rows are read from the database
+//
+// The rows are closed before StreamContext returns.  In the event handle
+// returns an error, the stream is stopped and the error is returned.
+func (w *MySQLModel) StreamContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.engine().stream(ctx, w, fetchConfig, buildModel, streamConfig, handle)
+}
+
+// Count returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *MySQLModel) Count(fetchConfig BulkFetchConfig) (int64, error) {
+	return w.CountContext(context.Background(), fetchConfig)
+}
+
+// CountContext returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *MySQLModel) CountContext(ctx context.Context, fetchConfig BulkFetchConfig) (int64, error) {
+	return w.engine().count(ctx, w, fetchConfig)
+}
+
+// Exists returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *MySQLModel) Exists(fetchConfig BulkFetchConfig) (bool, error) {
+	return w.ExistsContext(context.Background(), fetchConfig)
+}
+
+// ExistsContext returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *MySQLModel) ExistsContext(ctx context.Context, fetchConfig BulkFetchConfig) (bool, error) {
+	return w.engine().exists(ctx, w, fetchConfig)
+}
+
+// Aggregate runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *MySQLModel) Aggregate(aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.AggregateContext(context.Background(), aggregateConfig)
+}
+
+// AggregateContext runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *MySQLModel) AggregateContext(ctx context.Context, aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.engine().aggregate(ctx, w, aggregateConfig)
+}
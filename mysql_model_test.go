@@ -0,0 +1,285 @@
+package surf_test
+
+import (
+	"database/sql"
+	"github.com/go-carrot/surf"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/guregu/null.v3"
+	"os"
+	"testing"
+)
+
+// mysqlModels returns a modelBuilder that builds a surf.MySQLModel
+func mysqlModels(dbConnection surf.Executor) modelBuilder {
+	return func(config surf.Configuration) surf.Model {
+		return &surf.MySQLModel{Database: dbConnection, Config: config}
+	}
+}
+
+// ================================
+// ========== Test Suite ==========
+// ================================
+
+type MySQLModelTestSuite struct {
+	ModelTestSuite
+	db *sql.DB
+}
+
+func (suite *MySQLModelTestSuite) SetupTest() {
+	// Enable logging
+	stackWriter := &StackWriter{}
+	surf.SetLogging(true, stackWriter)
+
+	// Opening + storing the connection
+	databaseUrl := os.Getenv("SERF_TEST_MYSQL_DATABASE_URL")
+	db, err := sql.Open("mysql", databaseUrl)
+	if err != nil {
+		suite.Fail("Failed to open database connection")
+	}
+
+	// Pinging the database
+	err = db.Ping()
+	if err != nil {
+		suite.Fail("Failed to communicate with database")
+	}
+
+	suite.db = db
+	suite.models = mysqlModels(db)
+}
+
+func (suite *MySQLModelTestSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+func (suite *MySQLModelTestSuite) TestInsert() {
+	// Create an Animal
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	err := rigby.Insert()
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), int64(0), rigby.Id)
+
+	// Cause a conflict to test errors are being thrown
+	rigbyTwo := NewAnimalWith(suite.models)
+	rigbyTwo.Name = "Rigby Two"
+	rigbyTwo.Slug = "rigby" // This should cause a conflict
+	rigbyTwo.Age = 3
+	err = rigbyTwo.Insert()
+	assert.NotNil(suite.T(), err)
+
+	// Clean up Rigby
+	rigby.Delete()
+}
+
+func (suite *MySQLModelTestSuite) TestBulkInsert() {
+	// Create Animals
+	var animals []surf.Model
+	for _, slug := range []string{"luna", "rae", "rigby"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = slug
+		animal.Slug = slug
+		animal.Age = 3
+		animals = append(animals, animal)
+	}
+	err := NewAnimalWith(suite.models).Model.(surf.BulkInserter).BulkInsert(animals)
+	assert.Nil(suite.T(), err)
+	for i, animal := range animals {
+		assert.NotEqual(suite.T(), int64(0), animal.(*Animal).Id)
+		if i > 0 {
+			assert.Equal(suite.T(), animals[i-1].(*Animal).Id+1, animal.(*Animal).Id)
+		}
+	}
+
+	// Create a toy, which should expand its owner
+	tennisBall := NewToyWith(suite.models)
+	tennisBall.Name = "tennis ball"
+	tennisBall.OwnerId = animals[1].(*Animal).Id
+	err = NewToyWith(suite.models).Model.(surf.BulkInserter).BulkInsert([]surf.Model{tennisBall})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "rae", tennisBall.Owner.Name)
+
+	// Clean up
+	tennisBall.Delete()
+	for _, animal := range animals {
+		animal.Delete()
+	}
+}
+
+func (suite *MySQLModelTestSuite) TestLoad() {
+	// Create an Animal
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	rigby.Insert()
+
+	// Verify it loads from id
+	rigbyIdLoad := NewAnimalWith(suite.models)
+	rigbyIdLoad.Id = rigby.Id
+	err := rigbyIdLoad.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), rigby.Slug, rigbyIdLoad.Slug)
+
+	// Verify it loads from slug
+	rigbySlugLoad := NewAnimalWith(suite.models)
+	rigbySlugLoad.Slug = rigby.Slug
+	err = rigbySlugLoad.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), rigby.Id, rigbySlugLoad.Id)
+
+	// Make sure an error is thrown when trying to load something that doesn't exist
+	dummyAnimal := NewAnimalWith(suite.models)
+	dummyAnimal.Slug = "wow-cool-cat"
+	err = dummyAnimal.Load()
+	assert.NotNil(suite.T(), err)
+
+	// Clean up Rigby
+	rigby.Delete()
+}
+
+func (suite *MySQLModelTestSuite) TestUpdate() {
+	// Create an Animal
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	rigby.Insert()
+
+	// Update
+	rigby.Age = 4
+	err := rigby.Update()
+	assert.Nil(suite.T(), err)
+
+	// Verify the update happened in the DB
+	rigbyVerification := NewAnimalWith(suite.models)
+	rigbyVerification.Id = rigby.Id
+	rigbyVerification.Load()
+	assert.Equal(suite.T(), 4, rigbyVerification.Age)
+
+	// Update something that doesn't exist
+	dummyAnimal := NewAnimalWith(suite.models)
+	dummyAnimal.Slug = "wow-cool-cat"
+	err = dummyAnimal.Update()
+	assert.NotNil(suite.T(), err)
+
+	// Clean up
+	rigby.Delete()
+}
+
+func (suite *MySQLModelTestSuite) TestDelete() {
+	// Create an Animal
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	rigby.Insert()
+
+	// Delete
+	err := rigby.Delete()
+	assert.Nil(suite.T(), err)
+
+	// Try to delete an animal that doesn't exist
+	err = rigby.Delete()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MySQLModelTestSuite) TestBulkFetch() {
+	// Create some Animals
+	luna := NewAnimalWith(suite.models)
+	luna.Name = "Luna"
+	luna.Slug = "luna"
+	luna.Age = 2
+	luna.Insert()
+
+	rae := NewAnimalWith(suite.models)
+	rae.Name = "Rae"
+	rae.Slug = "rae"
+	rae.Age = 2
+	rae.Insert()
+
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	rigby.Insert()
+
+	// Predicates
+	config := surf.BulkFetchConfig{
+		Limit: 10,
+		Predicates: []surf.Predicate{
+			{Field: "age", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{2}},
+			{Field: "name", PredicateType: surf.WHERE_IN, Values: []interface{}{"Luna", "Rae", "Rigby"}},
+		},
+	}
+	config.ConsumeSortQuery("-name")
+	animals, err := NewAnimalWith(suite.models).BulkFetch(config, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(animals))
+	assert.Equal(suite.T(), "Rae", animals[0].(*Animal).Name)
+	assert.Equal(suite.T(), "Luna", animals[1].(*Animal).Name)
+
+	// Invalid order by
+	config.ConsumeSortQuery("helloworld")
+	_, err = NewAnimalWith(suite.models).BulkFetch(config, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.NotNil(suite.T(), err)
+
+	// Clean up
+	luna.Delete()
+	rae.Delete()
+	rigby.Delete()
+}
+
+func (suite *MySQLModelTestSuite) TestStreamInTx() {
+	suite.streamInTx(suite.db, mysqlModels)
+}
+
+func (suite *MySQLModelTestSuite) TestNestedModel() {
+	// Create an Animal
+	cat := NewAnimalWith(suite.models)
+	cat.Name = "Luna"
+	cat.Slug = "luna"
+	cat.Age = 2
+	cat.Insert()
+
+	// Create a toy, which should expand its owner
+	sock := NewToyWith(suite.models)
+	sock.Name = "sock"
+	sock.OwnerId = cat.Id
+	sock.SecondOwnerId = null.IntFrom(cat.Id)
+	err := sock.Insert()
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), int64(0), sock.Id)
+	assert.Equal(suite.T(), cat.Id, sock.Owner.Id)
+	assert.Equal(suite.T(), cat.Id, sock.SecondOwner.Id)
+
+	// Load all toys
+	toys, err := NewToyWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+		Limit: 10,
+		OrderBys: []surf.OrderBy{
+			{Field: "id", Type: surf.ORDER_BY_ASC},
+		},
+	}, func() surf.Model {
+		return NewToyWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(toys))
+	assert.Equal(suite.T(), "Luna", toys[0].(*Toy).Owner.Name)
+
+	// Clean up
+	sock.Delete()
+	cat.Delete()
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestMySQLModelTestSuite(t *testing.T) {
+	suite.Run(t, new(MySQLModelTestSuite))
+}
@@ -8,20 +8,90 @@ const (
 	ORDER_BY_DESC
 )
 
+// NullsOrder is an enumeration of where NULL values are placed
+// by an order by
+type NullsOrder int
+
+const (
+	NULLS_DEFAULT NullsOrder = iota // The default of the database
+	NULLS_FIRST
+	NULLS_LAST
+)
+
 // OrderBy is the definition of a single order by clause
 type OrderBy struct {
 	Field string
 	Type  OrderByType
+	Nulls NullsOrder
+
+	// Rank orders by the relevance of a full-text search rather than
+	// the value of the Field, which must be the field of the search
+	Rank *TextSearch
 }
 
 // ToString converts an OrderBy to SQL
-func (ob *OrderBy) toString() string {
-	obType := ""
+func (ob *OrderBy) toString(dialect Dialect) string {
+	return ob.expressionToString(dialect, dialect.QuoteIdentifier(ob.Field))
+}
+
+// expressionToString converts an OrderBy of the SQL expression to SQL
+//
+// In the event the Dialect has no `NULLS FIRST` or `NULLS LAST`, the
+// NULL values are ordered by a CASE before the expression.
+func (ob *OrderBy) expressionToString(dialect Dialect, expression string) string {
+	switch {
+	case ob.Nulls == NULLS_DEFAULT:
+		return expression + ob.typeString()
+	case dialect.NullsOrdering():
+		return expression + ob.typeString() + ob.nullsString()
+	case ob.Nulls == NULLS_FIRST:
+		return "CASE WHEN " + expression + " IS NULL THEN 0 ELSE 1 END, " + expression + ob.typeString()
+	}
+	return "CASE WHEN " + expression + " IS NULL THEN 1 ELSE 0 END, " + expression + ob.typeString()
+}
+
+// typeString converts the direction of an OrderBy to SQL
+func (ob *OrderBy) typeString() string {
 	switch ob.Type {
 	case ORDER_BY_ASC:
-		obType = " ASC"
+		return " ASC"
 	case ORDER_BY_DESC:
-		obType = " DESC"
+		return " DESC"
+	}
+	return ""
+}
+
+// nullsString converts the NullsOrder of an OrderBy to SQL
+func (ob *OrderBy) nullsString() string {
+	switch ob.Nulls {
+	case NULLS_FIRST:
+		return " NULLS FIRST"
+	case NULLS_LAST:
+		return " NULLS LAST"
+	}
+	return ""
+}
+
+// sortString converts an OrderBy to the format of a `sort` query parameter
+func (ob *OrderBy) sortString() string {
+	sort := ob.Field
+	if ob.Type == ORDER_BY_DESC {
+		sort = "-" + sort
+	}
+	switch ob.Nulls {
+	case NULLS_FIRST:
+		sort += ":nulls_first"
+	case NULLS_LAST:
+		sort += ":nulls_last"
+	}
+	return sort
+}
+
+// seekOperator returns the operator that matches the values that
+// come after a value in the order of the OrderBy
+func (ob *OrderBy) seekOperator() string {
+	if ob.Type == ORDER_BY_DESC {
+		return "<"
 	}
-	return ob.Field + obType
+	return ">"
 }
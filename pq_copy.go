@@ -0,0 +1,203 @@
+package surf
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// CopyConfig is the configuration of a CopyIn or CopyOut
+type CopyConfig struct {
+	// BatchSize is the number of rows between each call to Progress,
+	// which defaults to 10000
+	BatchSize int
+
+	// Progress is called with the total number of rows that have
+	// been copied after every batch, and once the copy is complete
+	Progress func(rows int64)
+}
+
+// batchSize returns the BatchSize of the CopyConfig, or its default
+func (c CopyConfig) batchSize() int64 {
+	if c.BatchSize > 0 {
+		return int64(c.BatchSize)
+	}
+	return 10000
+}
+
+// progress calls Progress if it has been set
+func (c CopyConfig) progress(rows int64) {
+	if c.Progress != nil {
+		c.Progress(rows)
+	}
+}
+
+// CopySource returns the next Model to be copied by CopyIn, or
+// nil once there are no more models
+type CopySource func() (Model, error)
+
+// CopyModels returns a CopySource of an array of models
+func CopyModels(models []Model) CopySource {
+	i := 0
+	return func() (Model, error) {
+		if i >= len(models) {
+			return nil, nil
+		}
+		i++
+		return models[i-1], nil
+	}
+}
+
+// preparer is the interface of an Executor that can prepare statements,
+// which is satisfied by a *sql.Tx and a *Tx
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// CopyIn streams models into the table with `COPY FROM STDIN`, returning
+// the number of rows written
+func (w *PqModel) CopyIn(source CopySource, copyConfig CopyConfig) (int64, error) {
+	return w.CopyInContext(context.Background(), source, copyConfig)
+}
+
+// CopyInContext streams models into the table with `COPY FROM STDIN`,
+// returning the number of rows written
+//
+// Only the Insertable fields of the models are copied, and the models are
+// not scanned back.  As github.com/lib/pq requires a COPY to be run inside
+// of a transaction, a transaction is started if Database is a *sql.DB.
+// The COPY is all or nothing, so no rows are written in the event of an error.
+func (w *PqModel) CopyInContext(ctx context.Context, source CopySource, copyConfig CopyConfig) (int64, error) {
+	ctx, db := executor(ctx, w.Database, PostgresDialect{})
+	switch tv := db.(type) {
+	case *sql.DB:
+		var rows int64
+		err := WithTxContext(ctx, tv, func(tx *Tx) error {
+			var err error
+			rows, err = w.copyIn(ctx, tx, source, copyConfig)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		return rows, nil
+	case preparer:
+		return w.copyIn(ctx, tv, source, copyConfig)
+	}
+	return 0, errors.New("CopyIn requires a Database that is a *sql.DB, *sql.Tx or *surf.Tx")
+}
+
+// copyIn runs a `COPY FROM STDIN` in the transaction tx
+func (w *PqModel) copyIn(ctx context.Context, tx preparer, source CopySource, copyConfig CopyConfig) (int64, error) {
+	dialect := PostgresDialect{}
+
+	// Get Insertable Fields
+	var insertableFields []Field
+	for _, field := range w.Config.Fields {
+		if field.Insertable {
+			insertableFields = append(insertableFields, field)
+		}
+	}
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("COPY ")
+	queryBuffer.WriteString(dialect.QuoteIdentifier(w.Config.TableName))
+	queryBuffer.WriteString(" (")
+	for i, field := range insertableFields {
+		queryBuffer.WriteString(dialect.QuoteIdentifier(field.Name))
+		if (i + 1) < len(insertableFields) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	queryBuffer.WriteString(") FROM STDIN")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(dialect, query)
+
+	// Prepare Query
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	// Stream each model
+	var rows int64
+	for {
+		model, err := source()
+		if err != nil {
+			return 0, err
+		}
+		if model == nil {
+			break
+		}
+
+		var valueFields []interface{}
+		for _, field := range model.GetConfiguration().Fields {
+			if field.Insertable {
+				valueFields = append(valueFields, field.Pointer)
+			}
+		}
+		_, err = stmt.ExecContext(ctx, valueFields...)
+		if err != nil {
+			return 0, err
+		}
+
+		rows++
+		if rows%copyConfig.batchSize() == 0 {
+			copyConfig.progress(rows)
+		}
+	}
+
+	// Flush the stream
+	_, err = stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if rows == 0 || rows%copyConfig.batchSize() != 0 {
+		copyConfig.progress(rows)
+	}
+	return rows, nil
+}
+
+// CopyOut streams the rows of fetchConfig into freshly built models,
+// calling handle with each of them, and returns the number of rows read
+func (w *PqModel) CopyOut(fetchConfig BulkFetchConfig, buildModel BuildModel, copyConfig CopyConfig, handle func(Model) error) (int64, error) {
+	return w.CopyOutContext(context.Background(), fetchConfig, buildModel, copyConfig, handle)
+}
+
+// CopyOutContext streams the rows of fetchConfig into freshly built models,
+// calling handle with each of them, and returns the number of rows read
+//
+// This is the counterpart of CopyIn, but as github.com/lib/pq does not
+// support `COPY TO STDOUT`, the rows are read by a Stream of a SELECT, one
+// row at a time, rather than by `COPY`.  As with a Stream, the rows are
+// filtered, ordered and limited by fetchConfig, and the rows that are soft
+// deleted are excluded, unless ctx is from WithDeleted or OnlyDeleted.
+// Foreign references are not expanded.  In the event handle returns an
+// error, the copy is stopped and the error is returned.
+func (w *PqModel) CopyOutContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel, copyConfig CopyConfig, handle func(Model) error) (int64, error) {
+	var count int64
+	err := w.StreamContext(ctx, fetchConfig, buildModel, StreamConfig{}, func(model Model) error {
+		err := handle(model)
+		if err != nil {
+			return err
+		}
+
+		count++
+		if count%copyConfig.batchSize() == 0 {
+			copyConfig.progress(count)
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if count == 0 || count%copyConfig.batchSize() != 0 {
+		copyConfig.progress(count)
+	}
+	return count, nil
+}
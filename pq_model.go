@@ -1,19 +1,23 @@
 package surf
 
 import (
-	"bytes"
-	"database/sql"
-	"errors"
-	"fmt"
-	"strconv"
+	"context"
 )
 
 // PqModel is a github.com/lib/pq implementation of a Model
 type PqModel struct {
-	Database *sql.DB       `json:"-"`
+	Database Executor      `json:"-"`
 	Config   Configuration `json:"-"`
 }
 
+// PqModel implements every optional interface of a Model
+var _ fullModel = (*PqModel)(nil)
+
+// engine returns the sqlEngine that runs the queries for the model
+func (w *PqModel) engine() sqlEngine {
+	return sqlEngine{Database: w.Database, Dialect: PostgresDialect{}}
+}
+
 // GetConfiguration returns the configuration for the model
 func (w *PqModel) GetConfiguration() *Configuration {
 	return &w.Config
@@ -21,285 +25,215 @@ func (w *PqModel) GetConfiguration() *Configuration {
 
 // Insert inserts the model into the database
 func (w *PqModel) Insert() error {
-	// Get Insertable Fields
-	var insertableFields []Field
-	for _, field := range w.Config.Fields {
-		if field.Insertable {
-			insertableFields = append(insertableFields, field)
-		}
-	}
-
-	// Generate Query
-	var queryBuffer bytes.Buffer
-	queryBuffer.WriteString("INSERT INTO ")
-	queryBuffer.WriteString(w.Config.TableName)
-	queryBuffer.WriteString("(")
-	for i, field := range insertableFields {
-		queryBuffer.WriteString(field.Name)
-		if (i + 1) < len(insertableFields) {
-			queryBuffer.WriteString(", ")
-		}
-	}
-	queryBuffer.WriteString(") VALUES(")
-	for i := range insertableFields {
-		queryBuffer.WriteString("$")
-		queryBuffer.WriteString(strconv.Itoa(i + 1))
-		if (i + 1) < len(insertableFields) {
-			queryBuffer.WriteString(", ")
-		}
-	}
-	queryBuffer.WriteString(") RETURNING ")
-	for i, field := range w.Config.Fields {
-		queryBuffer.WriteString(field.Name)
-		if (i + 1) < len(w.Config.Fields) {
-			queryBuffer.WriteString(", ")
-		}
-	}
-	queryBuffer.WriteString(";")
-
-	// Get Value Fields
-	var valueFields []interface{}
-	for _, value := range insertableFields {
-		valueFields = append(valueFields, value.Pointer)
-	}
-
-	// Log Query
-	query := queryBuffer.String()
-	PrintSqlQuery(query, valueFields...)
-
-	// Execute Query
-	row := w.Database.QueryRow(query, valueFields...)
-	err := consumeRow(w, row)
-	if err != nil {
-		return err
-	}
-
-	// Expand foreign references
-	return expandForeign(w)
+	return w.InsertContext(context.Background())
+}
+
+// InsertContext inserts the model into the database
+func (w *PqModel) InsertContext(ctx context.Context) error {
+	return w.engine().insert(ctx, w)
+}
+
+// BulkInsert inserts an array of models into the database
+func (w *PqModel) BulkInsert(models []Model) error {
+	return w.BulkInsertContext(context.Background(), models)
+}
+
+// BulkInsertContext inserts an array of models into the database
+func (w *PqModel) BulkInsertContext(ctx context.Context, models []Model) error {
+	return w.engine().bulkInsert(ctx, w, models)
+}
+
+// Upsert inserts the model into the database, or resolves a
+// conflict with an existing row
+func (w *PqModel) Upsert(upsertConfig UpsertConfig) error {
+	return w.UpsertContext(context.Background(), upsertConfig)
+}
+
+// UpsertContext inserts the model into the database, or resolves a
+// conflict with an existing row
+func (w *PqModel) UpsertContext(ctx context.Context, upsertConfig UpsertConfig) error {
+	return w.engine().upsert(ctx, w, upsertConfig)
 }
 
 // Load loads the model from the database from its unique identifier
 // and then loads those values into the struct
 func (w *PqModel) Load() error {
-	// Get Unique Identifier
-	uniqueIdentifierField, err := getUniqueIdentifier(w)
-	if err != nil {
-		return err
-	}
-
-	// Generate Query
-	var queryBuffer bytes.Buffer
-	queryBuffer.WriteString("SELECT ")
-	for i, field := range w.Config.Fields {
-		queryBuffer.WriteString(field.Name)
-		if (i + 1) < len(w.Config.Fields) {
-			queryBuffer.WriteString(", ")
-		}
-	}
-	queryBuffer.WriteString(" FROM ")
-	queryBuffer.WriteString(w.Config.TableName)
-	queryBuffer.WriteString(" WHERE ")
-	queryBuffer.WriteString(uniqueIdentifierField.Name)
-	queryBuffer.WriteString("=$1;")
-
-	// Log Query
-	query := queryBuffer.String()
-	PrintSqlQuery(query, uniqueIdentifierField.Pointer)
-
-	// Execute Query
-	row := w.Database.QueryRow(query, uniqueIdentifierField.Pointer)
-	err = consumeRow(w, row)
-	if err != nil {
-		return err
-	}
-
-	// Expand foreign references
-	return expandForeign(w)
+	return w.LoadContext(context.Background())
+}
+
+// LoadContext loads the model from the database from its unique identifier
+// and then loads those values into the struct
+func (w *PqModel) LoadContext(ctx context.Context) error {
+	return w.engine().load(ctx, w, nil)
+}
+
+// LoadFields loads the fields of names of the model from the database
+// from its unique identifier, leaving its other fields untouched
+func (w *PqModel) LoadFields(names []string) error {
+	return w.LoadFieldsContext(context.Background(), names)
+}
+
+// LoadFieldsContext loads the fields of names of the model from the database
+// from its unique identifier, leaving its other fields untouched
+func (w *PqModel) LoadFieldsContext(ctx context.Context, names []string) error {
+	return w.engine().load(ctx, w, names)
 }
 
 // Update updates the model with the current values in the struct
 func (w *PqModel) Update() error {
-	// Get Unique Identifier
-	uniqueIdentifierField, err := getUniqueIdentifier(w)
-	if err != nil {
-		return err
-	}
-
-	// Get updatable fields
-	var updatableFields []Field
-	for _, field := range w.Config.Fields {
-		if field.Updatable {
-			updatableFields = append(updatableFields, field)
-		}
-	}
-
-	// Generate Query
-	var queryBuffer bytes.Buffer
-	queryBuffer.WriteString("UPDATE ")
-	queryBuffer.WriteString(w.Config.TableName)
-	queryBuffer.WriteString(" SET ")
-	for i, field := range updatableFields {
-		queryBuffer.WriteString(field.Name)
-		queryBuffer.WriteString("=$")
-		queryBuffer.WriteString(strconv.Itoa(i + 1))
-		if (i + 1) < len(updatableFields) {
-			queryBuffer.WriteString(", ")
-		}
-	}
-	queryBuffer.WriteString(" WHERE ")
-	queryBuffer.WriteString(uniqueIdentifierField.Name)
-	queryBuffer.WriteString("=$")
-	queryBuffer.WriteString(strconv.Itoa(len(updatableFields) + 1))
-	queryBuffer.WriteString(" RETURNING ")
-	for i, field := range w.Config.Fields {
-		queryBuffer.WriteString(field.Name)
-		if (i + 1) < len(w.Config.Fields) {
-			queryBuffer.WriteString(", ")
-		}
-	}
-	queryBuffer.WriteString(";")
-
-	// Get Value Fields
-	var valueFields []interface{}
-	for _, value := range updatableFields {
-		valueFields = append(valueFields, value.Pointer)
-	}
-	valueFields = append(valueFields, uniqueIdentifierField.Pointer)
-
-	// Log Query
-	query := queryBuffer.String()
-	PrintSqlQuery(query, valueFields...)
-
-	// Execute Query
-	row := w.Database.QueryRow(query, valueFields...)
-	err = consumeRow(w, row)
-	if err != nil {
-		return err
-	}
-
-	// Expand foreign references
-	return expandForeign(w)
-}
-
-// Delete deletes the model
+	return w.UpdateContext(context.Background())
+}
+
+// UpdateContext updates the model with the current values in the struct
+func (w *PqModel) UpdateContext(ctx context.Context) error {
+	return w.engine().update(ctx, w)
+}
+
+// BulkUpdate sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *PqModel) BulkUpdate(predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.BulkUpdateContext(context.Background(), predicates, values)
+}
+
+// BulkUpdateContext sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *PqModel) BulkUpdateContext(ctx context.Context, predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.engine().bulkUpdate(ctx, w, predicates, values)
+}
+
+// Delete deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
 func (w *PqModel) Delete() error {
-	// Get Unique Identifier
-	uniqueIdentifierField, err := getUniqueIdentifier(w)
-	if err != nil {
-		return err
-	}
-
-	// Generate Query
-	var queryBuffer bytes.Buffer
-	queryBuffer.WriteString("DELETE FROM ")
-	queryBuffer.WriteString(w.Config.TableName)
-	queryBuffer.WriteString(" WHERE ")
-	queryBuffer.WriteString(uniqueIdentifierField.Name)
-	queryBuffer.WriteString("=$1;")
-
-	// Log Query
-	query := queryBuffer.String()
-	PrintSqlQuery(query, uniqueIdentifierField.Pointer)
-
-	// Execute Query
-	res, err := w.Database.Exec(queryBuffer.String(), uniqueIdentifierField.Pointer)
-	if err != nil {
-		return err
-	}
-	numRows, _ := res.RowsAffected()
-	if numRows != 1 {
-		return errors.New("Nothing was deleted")
-	}
-	return nil
+	return w.DeleteContext(context.Background())
+}
+
+// DeleteContext deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *PqModel) DeleteContext(ctx context.Context) error {
+	return w.engine().delete(ctx, w)
+}
+
+// HardDelete deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *PqModel) HardDelete() error {
+	return w.HardDeleteContext(context.Background())
+}
+
+// HardDeleteContext deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *PqModel) HardDeleteContext(ctx context.Context) error {
+	return w.engine().hardDelete(ctx, w)
+}
+
+// Restore undoes the soft delete of the model
+func (w *PqModel) Restore() error {
+	return w.RestoreContext(context.Background())
+}
+
+// RestoreContext undoes the soft delete of the model
+func (w *PqModel) RestoreContext(ctx context.Context) error {
+	return w.engine().restore(ctx, w)
+}
+
+// BulkDelete deletes all rows that match predicates, or soft deletes them
+// in the event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *PqModel) BulkDelete(predicates []Predicate) (int64, error) {
+	return w.BulkDeleteContext(context.Background(), predicates)
+}
+
+// BulkDeleteContext deletes all rows that match predicates, or soft deletes
+// them in the event that it has a DeletedAtField, returning the number of
+// rows that were deleted
+func (w *PqModel) BulkDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	return w.engine().bulkDelete(ctx, w, predicates)
+}
+
+// BulkHardDelete deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *PqModel) BulkHardDelete(predicates []Predicate) (int64, error) {
+	return w.BulkHardDeleteContext(context.Background(), predicates)
+}
+
+// BulkHardDeleteContext deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows that
+// were deleted
+func (w *PqModel) BulkHardDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	return w.engine().bulkHardDelete(ctx, w, predicates)
 }
 
 // BulkFetch gets an array of models
 func (w *PqModel) BulkFetch(fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
-	// Set up values
-	values := make([]interface{}, 0)
-
-	// Generate query
-	var queryBuffer bytes.Buffer
-	queryBuffer.WriteString("SELECT ")
-	for i, field := range w.Config.Fields {
-		queryBuffer.WriteString(field.Name)
-		if (i + 1) < len(w.Config.Fields) {
-			queryBuffer.WriteString(", ")
-		}
-	}
-	queryBuffer.WriteString(" FROM ")
-	queryBuffer.WriteString(buildModel().GetConfiguration().TableName)
-	if len(fetchConfig.Predicates) > 0 {
-		// WHERE
-		queryBuffer.WriteString(" ")
-		predicatesStr, predicateValues := predicatesToString(1, fetchConfig.Predicates)
-
-		values = append(values, predicateValues...)
-		queryBuffer.WriteString(predicatesStr)
-	}
-	if len(fetchConfig.OrderBys) > 0 {
-		queryBuffer.WriteString(" ORDER BY ")
-	}
-	for i, orderBy := range fetchConfig.OrderBys {
-		// Validate that the orderBy.Field is a field
-		valid := false
-		for _, field := range w.Config.Fields {
-			if field.Name == orderBy.Field {
-				valid = true
-				break
-			}
-		}
-		if !valid {
-			return nil, fmt.Errorf("Could not order table '%v' by the invalid column '%v'",
-				w.Config.TableName, orderBy.Field)
-		}
-		// Write to query
-		queryBuffer.WriteString(orderBy.toString())
-		if (i + 1) < len(fetchConfig.OrderBys) {
-			queryBuffer.WriteString(", ")
-		}
-	}
-	queryBuffer.WriteString(" LIMIT ")
-	queryBuffer.WriteString(strconv.Itoa(fetchConfig.Limit))
-	queryBuffer.WriteString(" OFFSET ")
-	queryBuffer.WriteString(strconv.Itoa(fetchConfig.Offset))
-	queryBuffer.WriteString(";")
-
-	// Log Query
-	query := queryBuffer.String()
-	PrintSqlQuery(query, values...)
-
-	// Execute Query
-	rows, err := w.Database.Query(query, values...)
-	if err != nil {
-		return nil, err
-	}
-
-	// Stuff into []Model
-	models := make([]Model, 0)
-	for rows.Next() {
-		model := buildModel()
-
-		// Consume Rows
-		fields := model.GetConfiguration().Fields
-		var s []interface{}
-		for _, value := range fields {
-			s = append(s, value.Pointer)
-		}
-		err := rows.Scan(s...)
-		if err != nil {
-			return nil, err
-		}
-
-		models = append(models, model.(Model))
-	}
-
-	// Expand foreign references
-	err = expandForeigns(buildModel, models)
-	if err != nil {
-		return nil, err
-	}
-
-	// OK
-	return models, nil
+	return w.BulkFetchContext(context.Background(), fetchConfig, buildModel)
+}
+
+// BulkFetchContext gets an array of models
+func (w *PqModel) BulkFetchContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	return w.engine().bulkFetch(ctx, w, fetchConfig, buildModel)
+}
+
+// Stream calls handle with each of the models of a bulk fetch,
+// as the rows are read from the database
+func (w *PqModel) Stream(fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.StreamContext(context.Background(), fetchConfig, buildModel, streamConfig, handle)
+}
+
+// StreamContext calls handle with each of the models of a bulk fetch,
+// as the rows are read from the database
+//
+// The rows are closed before StreamContext returns.  In the event handle
+// returns an error, the stream is stopped and the error is returned.
+func (w *PqModel) StreamContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.engine().stream(ctx, w, fetchConfig, buildModel, streamConfig, handle)
+}
+
+// Count returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *PqModel) Count(fetchConfig BulkFetchConfig) (int64, error) {
+	return w.CountContext(context.Background(), fetchConfig)
+}
+
+// CountContext returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *PqModel) CountContext(ctx context.Context, fetchConfig BulkFetchConfig) (int64, error) {
+	return w.engine().count(ctx, w, fetchConfig)
+}
+
+// Exists returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *PqModel) Exists(fetchConfig BulkFetchConfig) (bool, error) {
+	return w.ExistsContext(context.Background(), fetchConfig)
+}
+
+// ExistsContext returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *PqModel) ExistsContext(ctx context.Context, fetchConfig BulkFetchConfig) (bool, error) {
+	return w.engine().exists(ctx, w, fetchConfig)
+}
+
+// Aggregate runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *PqModel) Aggregate(aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.AggregateContext(context.Background(), aggregateConfig)
+}
+
+// AggregateContext runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *PqModel) AggregateContext(ctx context.Context, aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.engine().aggregate(ctx, w, aggregateConfig)
+}
+
+// Search gets the models of a bulk fetch that match a full-text search,
+// ordered by their relevance to the search
+func (w *PqModel) Search(fetchConfig BulkFetchConfig, search TextSearch, buildModel BuildModel) ([]SearchResult, error) {
+	return w.SearchContext(context.Background(), fetchConfig, search, buildModel)
+}
+
+// SearchContext gets the models of a bulk fetch that match a full-text search,
+// ordered by their relevance to the search
+//
+// The OrderBys of fetchConfig break ties between models of the same relevance.
+func (w *PqModel) SearchContext(ctx context.Context, fetchConfig BulkFetchConfig, search TextSearch, buildModel BuildModel) ([]SearchResult, error) {
+	return w.engine().search(ctx, w, fetchConfig, search, buildModel)
 }
@@ -1,16 +1,29 @@
 package surf_test
 
 import (
+	"context"
 	"database/sql"
 	"github.com/go-carrot/surf"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/guregu/null.v3"
 	"os"
+	"strings"
 	"testing"
 )
 
+// modelBuilder wraps a surf.Configuration in the surf.Model
+// implementation that is being tested
+type modelBuilder func(surf.Configuration) surf.Model
+
+// pqModels returns a modelBuilder that builds a surf.PqModel
+func pqModels(dbConnection surf.Executor) modelBuilder {
+	return func(config surf.Configuration) surf.Model {
+		return &surf.PqModel{Database: dbConnection, Config: config}
+	}
+}
+
 // =================================
 // ========== Place Model ==========
 // =================================
@@ -123,51 +136,61 @@ type Animal struct {
 }
 
 func NewAnimal(dbConnection *sql.DB) *Animal {
+	return NewAnimalWith(pqModels(dbConnection))
+}
+
+func NewAnimalWith(models modelBuilder) *Animal {
 	animal := new(Animal)
-	return animal.Prep(dbConnection)
+	return animal.PrepWith(models)
 }
 
 func (a *Animal) Prep(dbConnection *sql.DB) *Animal {
-	a.Model = &surf.PqModel{
-		Database: dbConnection,
-		Config: surf.Configuration{
-			TableName: "animals",
-			Fields: []surf.Field{
-				{
-					Pointer:          &a.Id,
-					Name:             "id",
-					UniqueIdentifier: true,
-					IsSet: func(pointer interface{}) bool {
-						pointerInt := *pointer.(*int64)
-						return pointerInt != 0
-					},
-				},
-				{
-					Pointer:          &a.Slug,
-					Name:             "slug",
-					UniqueIdentifier: true,
-					IsSet: func(pointer interface{}) bool {
-						pointerStr := *pointer.(*string)
-						return pointerStr != ""
-					},
-					Insertable: true,
-					Updatable:  true,
-				},
-				{
-					Pointer:    &a.Name,
-					Name:       "name",
-					Insertable: true,
-					Updatable:  true,
+	return a.PrepWith(pqModels(dbConnection))
+}
+
+func (a *Animal) PrepWith(models modelBuilder) *Animal {
+	a.Model = models(surf.Configuration{
+		TableName: "animals",
+		Fields: []surf.Field{
+			{
+				Pointer:          &a.Id,
+				Name:             "id",
+				UniqueIdentifier: true,
+				IsSet: func(pointer interface{}) bool {
+					pointerInt := *pointer.(*int64)
+					return pointerInt != 0
 				},
-				{
-					Pointer:    &a.Age,
-					Name:       "age",
-					Insertable: true,
-					Updatable:  true,
+				Sortable: true,
+			},
+			{
+				Pointer:          &a.Slug,
+				Name:             "slug",
+				UniqueIdentifier: true,
+				IsSet: func(pointer interface{}) bool {
+					pointerStr := *pointer.(*string)
+					return pointerStr != ""
 				},
+				Insertable: true,
+				Updatable:  true,
+			},
+			{
+				Pointer:    &a.Name,
+				Name:       "name",
+				Insertable: true,
+				Updatable:  true,
+				Filterable: true,
+				Sortable:   true,
+			},
+			{
+				Pointer:    &a.Age,
+				Name:       "age",
+				Insertable: true,
+				Updatable:  true,
+				Filterable: true,
+				Sortable:   true,
 			},
 		},
-	}
+	})
 	return a
 }
 
@@ -195,59 +218,204 @@ type Toy struct {
 }
 
 func NewToy(dbConnection *sql.DB) *Toy {
+	return NewToyWith(pqModels(dbConnection))
+}
+
+func NewToyWith(models modelBuilder) *Toy {
 	toy := new(Toy)
-	return toy.Prep(dbConnection)
+	return toy.PrepWith(models)
 }
 
 func (t *Toy) Prep(dbConnection *sql.DB) *Toy {
-	t.Model = &surf.PqModel{
-		Database: dbConnection,
-		Config: surf.Configuration{
-			TableName: "toys",
-			Fields: []surf.Field{
-				{Pointer: &t.Id, Name: "id", UniqueIdentifier: true,
-					IsSet: func(pointer interface{}) bool {
-						pointerInt := *pointer.(*int64)
-						return pointerInt != 0
-					},
+	return t.PrepWith(pqModels(dbConnection))
+}
+
+func (t *Toy) PrepWith(models modelBuilder) *Toy {
+	t.Model = models(surf.Configuration{
+		TableName: "toys",
+		Fields: []surf.Field{
+			{Pointer: &t.Id, Name: "id", UniqueIdentifier: true,
+				IsSet: func(pointer interface{}) bool {
+					pointerInt := *pointer.(*int64)
+					return pointerInt != 0
 				},
-				{Pointer: &t.Name, Name: "name", Insertable: true, Updatable: true},
-				{Pointer: &t.OwnerId, Name: "owner", Insertable: true, Updatable: true,
-					GetReference: func() (surf.BuildModel, string) {
-						return func() surf.Model {
-							return NewAnimal(dbConnection)
-						}, "id"
-					},
-					SetReference: func(model surf.Model) error {
-						t.Owner = model.(*Animal)
-						return nil
-					},
-					IsSet: func(pointer interface{}) bool {
-						pointerInt := *pointer.(*int64)
-						return pointerInt != 0
-					},
+			},
+			{Pointer: &t.Name, Name: "name", Insertable: true, Updatable: true, Filterable: true},
+			{Pointer: &t.OwnerId, Name: "owner", Insertable: true, Updatable: true,
+				GetReference: func() (surf.BuildModel, string) {
+					return func() surf.Model {
+						return NewAnimalWith(models)
+					}, "id"
 				},
-				{Pointer: &t.SecondOwnerId, Name: "second_owner", Insertable: true, Updatable: true,
-					GetReference: func() (surf.BuildModel, string) {
-						return func() surf.Model {
-							return NewAnimal(dbConnection)
-						}, "id"
-					},
-					SetReference: func(model surf.Model) error {
-						t.SecondOwner = model.(*Animal)
-						return nil
-					},
-					IsSet: func(pointer interface{}) bool {
-						pointerInt := *pointer.(*null.Int)
-						return pointerInt.Valid
-					},
+				SetReference: func(model surf.Model) error {
+					t.Owner = model.(*Animal)
+					return nil
+				},
+				IsSet: func(pointer interface{}) bool {
+					pointerInt := *pointer.(*int64)
+					return pointerInt != 0
+				},
+			},
+			{Pointer: &t.SecondOwnerId, Name: "second_owner", Insertable: true, Updatable: true, Filterable: true,
+				GetReference: func() (surf.BuildModel, string) {
+					return func() surf.Model {
+						return NewAnimalWith(models)
+					}, "id"
+				},
+				SetReference: func(model surf.Model) error {
+					t.SecondOwner = model.(*Animal)
+					return nil
+				},
+				IsSet: func(pointer interface{}) bool {
+					pointerInt := *pointer.(*null.Int)
+					return pointerInt.Valid
 				},
 			},
 		},
-	}
+	})
 	return t
 }
 
+// ====================================
+// ========== Document Model ==========
+// ====================================
+
+/**
+Represents:
+
+CREATE TABLE documents(
+  id serial PRIMARY KEY,
+  tags text[] NOT NULL,
+  data jsonb NOT NULL
+);
+*/
+type Document struct {
+	surf.Model
+	Id   int64          `json:"id"`
+	Tags pq.StringArray `json:"tags"`
+	Data string         `json:"data"`
+}
+
+func NewDocumentWith(models modelBuilder) *Document {
+	document := new(Document)
+	document.Model = models(surf.Configuration{
+		TableName: "documents",
+		Fields: []surf.Field{
+			{Pointer: &document.Id, Name: "id", UniqueIdentifier: true,
+				IsSet: func(pointer interface{}) bool {
+					pointerInt := *pointer.(*int64)
+					return pointerInt != 0
+				},
+			},
+			{Pointer: &document.Tags, Name: "tags", Insertable: true, Updatable: true},
+			{Pointer: &document.Data, Name: "data", Insertable: true, Updatable: true},
+		},
+	})
+	return document
+}
+
+// ================================
+// ========== Note Model ==========
+// ================================
+
+/**
+Represents:
+
+CREATE TABLE notes(
+  id serial PRIMARY KEY,
+  slug text UNIQUE,
+  body text NOT NULL,
+  version bigint NOT NULL DEFAULT 1
+);
+*/
+type Note struct {
+	surf.Model
+	Id      int64       `json:"id"`
+	Slug    null.String `json:"slug"`
+	Body    string      `json:"body"`
+	Version int64       `json:"version"`
+}
+
+func NewNoteWith(models modelBuilder) *Note {
+	note := new(Note)
+	note.Model = models(surf.Configuration{
+		TableName: "notes",
+		Fields: []surf.Field{
+			{Pointer: &note.Id, Name: "id", UniqueIdentifier: true,
+				IsSet: func(pointer interface{}) bool {
+					pointerInt := *pointer.(*int64)
+					return pointerInt != 0
+				},
+			},
+			{Pointer: &note.Slug, Name: "slug", UniqueIdentifier: true, Insertable: true, Updatable: true,
+				IsSet: func(pointer interface{}) bool {
+					return pointer.(*null.String).Valid
+				},
+			},
+			{Pointer: &note.Body, Name: "body", Insertable: true, Updatable: true},
+			{Pointer: &note.Version, Name: "version", Version: true},
+		},
+	})
+	return note
+}
+
+// ===================================
+// ========== Comment Model ==========
+// ===================================
+
+/**
+Represents:
+
+CREATE TABLE comments(
+  id serial PRIMARY KEY,
+  body text NOT NULL,
+  reply_to bigint REFERENCES comments(id) ON DELETE SET NULL,
+  deleted_at timestamp
+);
+*/
+type Comment struct {
+	surf.Model
+	Id        int64     `json:"id"`
+	Body      string    `json:"body"`
+	ReplyToId null.Int  `json:"-"`
+	ReplyTo   *Comment  `json:"reply_to"`
+	DeletedAt null.Time `json:"deleted_at"`
+}
+
+func NewCommentWith(models modelBuilder) *Comment {
+	comment := new(Comment)
+	comment.Model = models(surf.Configuration{
+		TableName:      "comments",
+		DeletedAtField: "deleted_at",
+		Fields: []surf.Field{
+			{Pointer: &comment.Id, Name: "id", UniqueIdentifier: true,
+				IsSet: func(pointer interface{}) bool {
+					pointerInt := *pointer.(*int64)
+					return pointerInt != 0
+				},
+			},
+			{Pointer: &comment.Body, Name: "body", Insertable: true, Updatable: true},
+			{Pointer: &comment.ReplyToId, Name: "reply_to", Insertable: true, Updatable: true,
+				GetReference: func() (surf.BuildModel, string) {
+					return func() surf.Model {
+						return NewCommentWith(models)
+					}, "id"
+				},
+				SetReference: func(model surf.Model) error {
+					comment.ReplyTo = model.(*Comment)
+					return nil
+				},
+				IsSet: func(pointer interface{}) bool {
+					pointerInt := *pointer.(*null.Int)
+					return pointerInt.Valid
+				},
+			},
+			{Pointer: &comment.DeletedAt, Name: "deleted_at"},
+		},
+	})
+	return comment
+}
+
 // ==================================================
 // ========== Animal Consume Failure Model ==========
 // ==================================================
@@ -318,7 +486,7 @@ func (ac *AnimalConsume) Prep(dbConnection *sql.DB) *AnimalConsume {
 // ================================
 
 type PqWorkerTestSuite struct {
-	suite.Suite
+	ModelTestSuite
 	db *sql.DB
 }
 
@@ -341,6 +509,7 @@ func (suite *PqWorkerTestSuite) SetupTest() {
 	}
 
 	suite.db = db
+	suite.models = pqModels(db)
 }
 
 func (suite *PqWorkerTestSuite) TearDownTest() {
@@ -368,6 +537,117 @@ func (suite *PqWorkerTestSuite) TestInsert() {
 	rigby.Delete()
 }
 
+func (suite *PqWorkerTestSuite) TestBulkInsert() {
+	// Create Animals
+	var animals []surf.Model
+	for _, slug := range []string{"luna", "rae", "rigby"} {
+		animal := NewAnimal(suite.db)
+		animal.Name = slug
+		animal.Slug = slug
+		animal.Age = 3
+		animals = append(animals, animal)
+	}
+	err := NewAnimal(suite.db).Model.(surf.BulkInserter).BulkInsert(animals)
+	assert.Nil(suite.T(), err)
+	for i, animal := range animals {
+		assert.NotEqual(suite.T(), int64(0), animal.(*Animal).Id)
+		if i > 0 {
+			assert.Equal(suite.T(), animals[i-1].(*Animal).Id+1, animal.(*Animal).Id)
+		}
+	}
+
+	// Create a toy, which should expand its owner
+	tennisBall := NewToy(suite.db)
+	tennisBall.Name = "tennis ball"
+	tennisBall.OwnerId = animals[1].(*Animal).Id
+	err = NewToy(suite.db).Model.(surf.BulkInserter).BulkInsert([]surf.Model{tennisBall})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "rae", tennisBall.Owner.Name)
+
+	// Clean up
+	tennisBall.Delete()
+	for _, animal := range animals {
+		animal.Delete()
+	}
+}
+
+func (suite *PqWorkerTestSuite) TestCopy() {
+	// Copy Animals in
+	var animals []surf.Model
+	for _, slug := range []string{"luna", "rae", "rigby"} {
+		animal := NewAnimal(suite.db)
+		animal.Name = slug
+		animal.Slug = slug
+		animal.Age = 3
+		animals = append(animals, animal)
+	}
+	var progress []int64
+	rows, err := NewAnimal(suite.db).Model.(*surf.PqModel).CopyIn(surf.CopyModels(animals), surf.CopyConfig{
+		BatchSize: 2,
+		Progress:  func(rows int64) { progress = append(progress, rows) },
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), rows)
+	assert.Equal(suite.T(), []int64{2, 3}, progress)
+
+	// Read them back out, as loaded models
+	var slugs []string
+	rows, err = NewAnimal(suite.db).Model.(*surf.PqModel).CopyOut(surf.BulkFetchConfig{Limit: 10}, func() surf.Model {
+		return NewAnimal(suite.db)
+	}, surf.CopyConfig{}, func(model surf.Model) error {
+		slugs = append(slugs, model.(*Animal).Slug)
+		assert.True(suite.T(), model.GetConfiguration().IsLoaded("slug"))
+		assert.Empty(suite.T(), model.GetConfiguration().Changed())
+		return nil
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), rows)
+	assert.ElementsMatch(suite.T(), []string{"luna", "rae", "rigby"}, slugs)
+
+	// Nothing is written in the event of a conflict
+	rigby := NewAnimal(suite.db)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	norbert := NewAnimal(suite.db)
+	norbert.Name = "Norbert"
+	norbert.Slug = "norbert"
+	rows, err = NewAnimal(suite.db).Model.(*surf.PqModel).CopyIn(surf.CopyModels([]surf.Model{norbert, rigby}), surf.CopyConfig{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), rows)
+	norbert.Id = 0
+	err = norbert.Load()
+	assert.NotNil(suite.T(), err)
+
+	// Soft deleted rows are only read when they are asked for
+	comment := NewCommentWith(suite.models)
+	comment.Body = "Hello"
+	err = comment.Insert()
+	assert.Nil(suite.T(), err)
+	err = comment.Delete()
+	assert.Nil(suite.T(), err)
+	readCommentIds := func(ctx context.Context) []int64 {
+		var ids []int64
+		_, err := NewCommentWith(suite.models).Model.(*surf.PqModel).CopyOutContext(ctx, surf.BulkFetchConfig{Limit: 100}, func() surf.Model {
+			return NewCommentWith(suite.models)
+		}, surf.CopyConfig{}, func(model surf.Model) error {
+			ids = append(ids, model.(*Comment).Id)
+			return nil
+		})
+		assert.Nil(suite.T(), err)
+		return ids
+	}
+	assert.NotContains(suite.T(), readCommentIds(context.Background()), comment.Id)
+	assert.Contains(suite.T(), readCommentIds(surf.WithDeleted(context.Background())), comment.Id)
+
+	// Clean up
+	comment.Model.(surf.SoftDeleter).HardDelete()
+	for _, slug := range slugs {
+		animal := NewAnimal(suite.db)
+		animal.Slug = slug
+		animal.Delete()
+	}
+}
+
 func (suite *PqWorkerTestSuite) TestLoad() {
 	// Create an Animal
 	rigby := NewAnimal(suite.db)
@@ -571,6 +851,73 @@ func (suite *PqWorkerTestSuite) TestUpdate() {
 	norbert.Delete()
 }
 
+func (suite *PqWorkerTestSuite) TestTextSearch() {
+	// Create some Animals
+	for _, name := range []string{"Big Dog", "Small Dog", "Big Big Cat", "Bird"} {
+		animal := NewAnimal(suite.db)
+		animal.Name = name
+		animal.Slug = strings.ToLower(strings.Replace(name, " ", "-", -1))
+		animal.Insert()
+	}
+
+	// searchSlugs returns the slugs of the animals that match search,
+	// ordered by their relevance
+	searchSlugs := func(search surf.TextSearch) []string {
+		models, err := NewAnimal(suite.db).BulkFetch(surf.BulkFetchConfig{
+			Limit:      10,
+			OrderBys:   []surf.OrderBy{search.OrderBy(), {Field: "slug", Type: surf.ORDER_BY_ASC}},
+			Predicates: []surf.Predicate{search.Predicate()},
+		}, func() surf.Model {
+			return NewAnimal(suite.db)
+		})
+		assert.Nil(suite.T(), err)
+		var slugs []string
+		for _, model := range models {
+			slugs = append(slugs, model.(*Animal).Slug)
+		}
+		return slugs
+	}
+
+	assert.Equal(suite.T(), []string{"big-big-cat", "big-dog"}, searchSlugs(surf.TextSearch{Field: "name", Query: "big"}))
+	assert.Equal(suite.T(), []string{"big-dog", "small-dog"}, searchSlugs(surf.TextSearch{Field: "name", Query: "dogs", Config: "english"}))
+	assert.Equal(suite.T(), []string{"big-dog"}, searchSlugs(surf.TextSearch{Field: "name", Query: "dog -small"}))
+	assert.Equal(suite.T(), []string{"big-big-cat", "bird"}, searchSlugs(surf.TextSearch{Field: "name", Query: "cat or bird"}))
+	assert.Nil(suite.T(), searchSlugs(surf.TextSearch{Field: "name", Query: "fish"}))
+
+	// Search with headlines
+	results, err := NewAnimal(suite.db).Model.(*surf.PqModel).Search(surf.BulkFetchConfig{
+		Limit:    10,
+		OrderBys: []surf.OrderBy{{Field: "slug", Type: surf.ORDER_BY_ASC}},
+	}, surf.TextSearch{Field: "name", Query: "big", Config: "english", Headline: true}, func() surf.Model {
+		return NewAnimal(suite.db)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(results))
+	assert.Equal(suite.T(), "big-big-cat", results[0].Model.(*Animal).Slug)
+	assert.Equal(suite.T(), "<b>Big</b> <b>Big</b> Cat", results[0].Headline)
+	assert.Equal(suite.T(), "big-dog", results[1].Model.(*Animal).Slug)
+	assert.Equal(suite.T(), "<b>Big</b> Dog", results[1].Headline)
+
+	// Search without headlines
+	results, err = NewAnimal(suite.db).Model.(*surf.PqModel).Search(surf.BulkFetchConfig{
+		Limit: 10,
+	}, surf.TextSearch{Field: "name", Query: "bird"}, func() surf.Model {
+		return NewAnimal(suite.db)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(results))
+	assert.Equal(suite.T(), "bird", results[0].Model.(*Animal).Slug)
+	assert.Equal(suite.T(), "", results[0].Headline)
+
+	// Keyset pagination can't seek past a rank
+	_, err = NewAnimal(suite.db).Model.(*surf.PqModel).Search(surf.BulkFetchConfig{
+		Keyset: true,
+	}, surf.TextSearch{Field: "name", Query: "big"}, func() surf.Model {
+		return NewAnimal(suite.db)
+	})
+	assert.NotNil(suite.T(), err)
+}
+
 func (suite *PqWorkerTestSuite) TestDelete() {
 	// Create an Animal
 	rigby := NewAnimal(suite.db)
@@ -693,6 +1040,42 @@ func (suite *PqWorkerTestSuite) TestNestedModel() {
 	sock.Delete()
 }
 
+func (suite *PqWorkerTestSuite) TestStreamInTx() {
+	suite.streamInTx(suite.db, pqModels)
+}
+
+func (suite *PqWorkerTestSuite) TestContext() {
+	// Create an Animal
+	rigby := NewAnimal(suite.db)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	err := rigby.Model.(surf.ContextModel).InsertContext(context.Background())
+	assert.Nil(suite.T(), err)
+
+	// Load it with a live context
+	rigbyLoad := NewAnimal(suite.db)
+	rigbyLoad.Id = rigby.Id
+	err = rigbyLoad.Model.(surf.ContextModel).LoadContext(context.Background())
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Rigby", rigbyLoad.Name)
+
+	// Nothing should run with a cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rigby.Age = 4
+	assert.NotNil(suite.T(), rigby.Model.(surf.ContextModel).UpdateContext(ctx))
+	assert.NotNil(suite.T(), rigby.Model.(surf.ContextModel).DeleteContext(ctx))
+	_, err = NewAnimal(suite.db).Model.(surf.ContextModel).BulkFetchContext(ctx, surf.BulkFetchConfig{Limit: 10}, func() surf.Model {
+		return NewAnimal(suite.db)
+	})
+	assert.NotNil(suite.T(), err)
+
+	// Clean up
+	err = rigby.Model.(surf.ContextModel).DeleteContext(context.Background())
+	assert.Nil(suite.T(), err)
+}
+
 func (suite *PqWorkerTestSuite) TestPredicates() {
 	// Create some Animals
 	luna := NewAnimal(suite.db)
@@ -817,5 +1200,5 @@ func (suite *PqWorkerTestSuite) PredicatePanic(predType surf.PredicateType, valu
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestPqWorkerTestSuite(t *testing.T) {
-	suite.Run(t, new(PqWorkerTestSuite))
+	suite.Run(t, &PqWorkerTestSuite{ModelTestSuite: ModelTestSuite{distinctFrom: true, postgres: true}})
 }
@@ -1,9 +1,5 @@
 package surf
 
-import (
-	"strconv"
-)
-
 type PredicateType int
 
 const (
@@ -18,6 +14,22 @@ const (
 	WHERE_GREATER_THAN_OR_EQUAL_TO
 	WHERE_LESS_THAN
 	WHERE_LESS_THAN_OR_EQUAL_TO
+	WHERE_AND // A group that matches if all of its Predicates match
+	WHERE_OR  // A group that matches if any of its Predicates match
+	WHERE_NOT // A group that matches if its Predicates don't all match
+	WHERE_BETWEEN
+	WHERE_ILIKE // Postgres only
+	WHERE_NOT_LIKE
+	WHERE_IS_DISTINCT_FROM
+	WHERE_REGEX           // `~`, Postgres only
+	WHERE_IREGEX          // `~*`, Postgres only
+	WHERE_ANY             // `= ANY(array)`, Postgres only
+	WHERE_CONTAINS        // `@>` of an array or JSONB, Postgres only
+	WHERE_CONTAINED_BY    // `<@` of an array or JSONB, Postgres only
+	WHERE_OVERLAPS        // `&&` of an array, Postgres only
+	WHERE_HAS_KEY         // `?` of a JSONB, Postgres only
+	WHERE_JSON_TEXT_EQUAL // `->> key = text` of a JSONB, Postgres only
+	WHERE_MATCHES         // `@@` full-text search of [config,] query, Postgres only
 )
 
 // getPredicateTypeString returns the predicate type string from it's value
@@ -43,6 +55,38 @@ func getPredicateTypeString(predicateType PredicateType) string {
 		return "WHERE_LESS_THAN"
 	case WHERE_LESS_THAN_OR_EQUAL_TO:
 		return "WHERE_LESS_THAN_OR_EQUAL_TO"
+	case WHERE_AND:
+		return "WHERE_AND"
+	case WHERE_OR:
+		return "WHERE_OR"
+	case WHERE_NOT:
+		return "WHERE_NOT"
+	case WHERE_BETWEEN:
+		return "WHERE_BETWEEN"
+	case WHERE_ILIKE:
+		return "WHERE_ILIKE"
+	case WHERE_NOT_LIKE:
+		return "WHERE_NOT_LIKE"
+	case WHERE_IS_DISTINCT_FROM:
+		return "WHERE_IS_DISTINCT_FROM"
+	case WHERE_REGEX:
+		return "WHERE_REGEX"
+	case WHERE_IREGEX:
+		return "WHERE_IREGEX"
+	case WHERE_ANY:
+		return "WHERE_ANY"
+	case WHERE_CONTAINS:
+		return "WHERE_CONTAINS"
+	case WHERE_CONTAINED_BY:
+		return "WHERE_CONTAINED_BY"
+	case WHERE_OVERLAPS:
+		return "WHERE_OVERLAPS"
+	case WHERE_HAS_KEY:
+		return "WHERE_HAS_KEY"
+	case WHERE_JSON_TEXT_EQUAL:
+		return "WHERE_JSON_TEXT_EQUAL"
+	case WHERE_MATCHES:
+		return "WHERE_MATCHES"
 	}
 	return "WHERE_IS_NOT_NULL"
 }
@@ -52,15 +96,71 @@ type Predicate struct {
 	Field         string
 	PredicateType PredicateType
 	Values        []interface{}
+
+	// Predicates are the children of a WHERE_AND, WHERE_OR or WHERE_NOT
+	// group, which may be groups themselves
+	Predicates []Predicate
+
+	// expression is written in place of the quoted Field, such as
+	// the aggregate that a HAVING predicate filters by
+	expression string
+}
+
+// And returns a group that matches if all of predicates match
+func And(predicates ...Predicate) Predicate {
+	return Predicate{PredicateType: WHERE_AND, Predicates: predicates}
+}
+
+// Or returns a group that matches if any of predicates match
+func Or(predicates ...Predicate) Predicate {
+	return Predicate{PredicateType: WHERE_OR, Predicates: predicates}
+}
+
+// Not returns a group that matches if predicates don't all match
+func Not(predicates ...Predicate) Predicate {
+	return Predicate{PredicateType: WHERE_NOT, Predicates: predicates}
+}
+
+// isGroup returns if the predicate is a WHERE_AND, WHERE_OR or WHERE_NOT group
+func (p *Predicate) isGroup() bool {
+	switch p.PredicateType {
+	case WHERE_AND, WHERE_OR, WHERE_NOT:
+		return true
+	}
+	return false
 }
 
 // toString will convert a predicate to it's query string, along with its values
 // to be passed along with the query
 //
 // This function will panic in the event that this is called on a malformed predicate
-func (p *Predicate) toString(valueIndex int) (string, []interface{}) {
+func (p *Predicate) toString(dialect Dialect, valueIndex int) (string, []interface{}) {
+	p.validate()
+
+	// Groups
+	switch p.PredicateType {
+	case WHERE_AND:
+		predicate, values := joinPredicates(dialect, valueIndex, " AND ", p.Predicates)
+		return "(" + predicate + ")", values
+	case WHERE_OR:
+		predicate, values := joinPredicates(dialect, valueIndex, " OR ", p.Predicates)
+		return "(" + predicate + ")", values
+	case WHERE_NOT:
+		predicate, values := joinPredicates(dialect, valueIndex, " AND ", p.Predicates)
+		return "NOT (" + predicate + ")", values
+	}
+
+	// Full-text search
+	if p.PredicateType == WHERE_MATCHES {
+		search := p.textSearch()
+		return search.matchesToString(dialect, valueIndex)
+	}
+
 	// Field
-	predicate := p.Field
+	predicate := dialect.QuoteIdentifier(p.Field)
+	if p.expression != "" {
+		predicate = p.expression
+	}
 
 	// Type
 	switch p.PredicateType {
@@ -94,6 +194,42 @@ func (p *Predicate) toString(valueIndex int) (string, []interface{}) {
 	case WHERE_LESS_THAN_OR_EQUAL_TO:
 		predicate += " <= "
 		break
+	case WHERE_BETWEEN:
+		predicate += " BETWEEN "
+		break
+	case WHERE_ILIKE:
+		predicate += " ILIKE "
+		break
+	case WHERE_NOT_LIKE:
+		predicate += " NOT LIKE "
+		break
+	case WHERE_IS_DISTINCT_FROM:
+		predicate += " IS DISTINCT FROM "
+		break
+	case WHERE_REGEX:
+		predicate += " ~ "
+		break
+	case WHERE_IREGEX:
+		predicate += " ~* "
+		break
+	case WHERE_ANY:
+		predicate += " = ANY"
+		break
+	case WHERE_CONTAINS:
+		predicate += " @> "
+		break
+	case WHERE_CONTAINED_BY:
+		predicate += " <@ "
+		break
+	case WHERE_OVERLAPS:
+		predicate += " && "
+		break
+	case WHERE_HAS_KEY:
+		predicate += " ? "
+		break
+	case WHERE_JSON_TEXT_EQUAL:
+		predicate += "->>"
+		break
 	default:
 		predicate += " IS NOT NULL"
 		break
@@ -104,13 +240,10 @@ func (p *Predicate) toString(valueIndex int) (string, []interface{}) {
 	switch p.PredicateType {
 	case WHERE_IN,
 		WHERE_NOT_IN:
-		if len(p.Values) == 0 {
-			panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates require at least one value.")
-		}
 		predicate += "("
 		for i, value := range p.Values {
 			values = append(values, value)
-			predicate += "$" + strconv.Itoa(valueIndex)
+			predicate += dialect.Placeholder(valueIndex)
 			valueIndex++
 			if i < len(p.Values)-1 {
 				predicate += ", "
@@ -124,12 +257,84 @@ func (p *Predicate) toString(valueIndex int) (string, []interface{}) {
 		WHERE_GREATER_THAN,
 		WHERE_GREATER_THAN_OR_EQUAL_TO,
 		WHERE_LESS_THAN,
-		WHERE_LESS_THAN_OR_EQUAL_TO:
+		WHERE_LESS_THAN_OR_EQUAL_TO,
+		WHERE_ILIKE,
+		WHERE_NOT_LIKE,
+		WHERE_IS_DISTINCT_FROM,
+		WHERE_REGEX,
+		WHERE_IREGEX,
+		WHERE_CONTAINS,
+		WHERE_CONTAINED_BY,
+		WHERE_OVERLAPS,
+		WHERE_HAS_KEY:
+		values = append(values, p.Values[0])
+		predicate += dialect.Placeholder(valueIndex)
+		break
+	case WHERE_ANY:
+		values = append(values, p.Values[0])
+		predicate += "(" + dialect.Placeholder(valueIndex) + ")"
+		break
+	case WHERE_BETWEEN:
+		values = append(values, p.Values[0], p.Values[1])
+		predicate += dialect.Placeholder(valueIndex) + " AND " + dialect.Placeholder(valueIndex+1)
+		break
+	case WHERE_JSON_TEXT_EQUAL:
+		values = append(values, p.Values[0], p.Values[1])
+		predicate += dialect.Placeholder(valueIndex) + " = " + dialect.Placeholder(valueIndex+1)
+		break
+	}
+
+	return predicate, values
+}
+
+// validate checks that the predicate has a known type, and the
+// number of values that its type requires
+//
+// This function will panic in the event that the predicate is malformed
+func (p *Predicate) validate() {
+	switch p.PredicateType {
+	case WHERE_IN,
+		WHERE_NOT_IN:
+		if len(p.Values) == 0 {
+			panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates require at least one value.")
+		}
+		break
+	case WHERE_LIKE,
+		WHERE_EQUAL,
+		WHERE_NOT_EQUAL,
+		WHERE_GREATER_THAN,
+		WHERE_GREATER_THAN_OR_EQUAL_TO,
+		WHERE_LESS_THAN,
+		WHERE_LESS_THAN_OR_EQUAL_TO,
+		WHERE_ILIKE,
+		WHERE_NOT_LIKE,
+		WHERE_IS_DISTINCT_FROM,
+		WHERE_REGEX,
+		WHERE_IREGEX,
+		WHERE_ANY,
+		WHERE_CONTAINS,
+		WHERE_CONTAINED_BY,
+		WHERE_OVERLAPS,
+		WHERE_HAS_KEY:
 		if len(p.Values) != 1 {
 			panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates require exactly one value.")
 		}
-		values = append(values, p.Values[0])
-		predicate += "$" + strconv.Itoa(valueIndex)
+		break
+	case WHERE_BETWEEN,
+		WHERE_JSON_TEXT_EQUAL:
+		if len(p.Values) != 2 {
+			panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates require exactly two values.")
+		}
+		break
+	case WHERE_MATCHES:
+		if len(p.Values) != 1 && len(p.Values) != 2 {
+			panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates require one or two values.")
+		}
+		for _, value := range p.Values {
+			if _, ok := value.(string); !ok {
+				panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates require string values.")
+			}
+		}
 		break
 	case WHERE_IS_NOT_NULL,
 		WHERE_IS_NULL:
@@ -137,31 +342,48 @@ func (p *Predicate) toString(valueIndex int) (string, []interface{}) {
 			panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates cannot have any values.")
 		}
 		break
+	case WHERE_AND,
+		WHERE_OR,
+		WHERE_NOT:
+		if len(p.Values) != 0 {
+			panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates cannot have any values.")
+		}
+		if len(p.Predicates) == 0 {
+			panic("`" + getPredicateTypeString(p.PredicateType) + "` predicates require at least one predicate.")
+		}
+		break
 	default:
 		panic("Unknown predicate type.")
 	}
-
-	return predicate, values
 }
 
 // predicatesToString converts an array of predicates to a query string, along with its values
 // to be passed along with the query
 //
 // This function will panic in the event that it encounters a malformed predicate
-func predicatesToString(valueIndex int, predicates []Predicate) (string, []interface{}) {
+func predicatesToString(dialect Dialect, valueIndex int, predicates []Predicate) (string, []interface{}) {
+	predicateStr, values := joinPredicates(dialect, valueIndex, " AND ", predicates)
+	if len(predicates) > 0 {
+		predicateStr = "WHERE " + predicateStr
+	}
+	return predicateStr, values
+}
+
+// joinPredicates converts an array of predicates to their query strings joined by
+// separator, along with its values to be passed along with the query
+//
+// This function will panic in the event that it encounters a malformed predicate
+func joinPredicates(dialect Dialect, valueIndex int, separator string, predicates []Predicate) (string, []interface{}) {
 	values := make([]interface{}, 0)
 
 	predicateStr := ""
-	if len(predicates) > 0 {
-		predicateStr += "WHERE "
-	}
 	for i, predicate := range predicates {
-		iPredicateStr, iValues := predicate.toString(valueIndex)
+		iPredicateStr, iValues := predicate.toString(dialect, valueIndex)
 		valueIndex += len(iValues)
 		values = append(values, iValues...)
 		predicateStr += iPredicateStr
 		if i < (len(predicates) - 1) {
-			predicateStr += " AND "
+			predicateStr += separator
 		}
 	}
 	return predicateStr, values
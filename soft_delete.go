@@ -0,0 +1,105 @@
+package surf
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/guregu/null.v3"
+	"reflect"
+	"time"
+)
+
+// deletedScope is an enumeration of which rows of a model with a
+// DeletedAtField are read
+type deletedScope int
+
+const (
+	deletedExcluded deletedScope = iota // Only read the rows that aren't soft deleted
+	deletedIncluded                     // Read every row
+	deletedOnly                         // Only read the rows that are soft deleted
+)
+
+// deletedContextKey is the context key that a deletedScope is stored under
+type deletedContextKey struct{}
+
+// WithDeleted returns a copy of ctx under which reads of models with a
+// DeletedAtField include the rows that have been soft deleted
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deletedContextKey{}, deletedIncluded)
+}
+
+// OnlyDeleted returns a copy of ctx under which reads of models with a
+// DeletedAtField only return the rows that have been soft deleted
+func OnlyDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deletedContextKey{}, deletedOnly)
+}
+
+// deletedAtField returns the field named by the DeletedAtField of config,
+// along with if config has a DeletedAtField
+//
+// This function will panic in the event that the DeletedAtField isn't
+// the Name of one of the Fields of config.
+func (c *Configuration) deletedAtField() (Field, bool) {
+	if c.DeletedAtField == "" {
+		return Field{}, false
+	}
+	for _, field := range c.Fields {
+		if field.Name == c.DeletedAtField {
+			return field, true
+		}
+	}
+	panic(fmt.Sprintf("DeletedAtField `%v` is not a field of table '%v'", c.DeletedAtField, c.TableName))
+}
+
+// deletedPredicate returns the predicate that matches the rows of config
+// that are soft deleted, or the rows that aren't in the event deleted is false
+func (c *Configuration) deletedPredicate(deleted bool) Predicate {
+	if deleted {
+		return Predicate{Field: c.DeletedAtField, PredicateType: WHERE_IS_NOT_NULL}
+	}
+	return Predicate{Field: c.DeletedAtField, PredicateType: WHERE_IS_NULL}
+}
+
+// scopeDeleted returns predicates, preceded by the predicate that filters
+// the rows of config by the deletedScope of ctx
+func (c *Configuration) scopeDeleted(ctx context.Context, predicates []Predicate) []Predicate {
+	if _, ok := c.deletedAtField(); !ok {
+		return predicates
+	}
+	switch ctx.Value(deletedContextKey{}) {
+	case deletedIncluded:
+		return predicates
+	case deletedOnly:
+		return append([]Predicate{c.deletedPredicate(true)}, predicates...)
+	}
+	return append([]Predicate{c.deletedPredicate(false)}, predicates...)
+}
+
+// scopeUndeleted returns predicates, filtered by the deletedScope of ctx as
+// with scopeDeleted, and preceded by the predicate that only matches the rows
+// of config that aren't soft deleted yet
+func (c *Configuration) scopeUndeleted(ctx context.Context, predicates []Predicate) []Predicate {
+	if ctx.Value(deletedContextKey{}) == deletedOnly {
+		predicates = c.scopeDeleted(ctx, predicates)
+	}
+	return append([]Predicate{c.deletedPredicate(false)}, predicates...)
+}
+
+// deletedAtValue returns the value of the type of field that is set by a
+// soft delete at deletedAt, or by a restore in the event deletedAt is nil
+//
+// The field may only be a `null.Time` or a `*time.Time`.
+func deletedAtValue(field Field, deletedAt *time.Time) interface{} {
+	switch field.Pointer.(type) {
+	case *null.Time:
+		return null.TimeFromPtr(deletedAt)
+	case **time.Time:
+		return deletedAt
+	default:
+		panic(fmt.Sprintf("DeletedAtField `%v` may only be a `null.Time` or a `*time.Time`, not `%T`", field.Name, field.Pointer))
+	}
+}
+
+// setFieldValue sets value into the pointer of field
+func setFieldValue(field Field, value interface{}) {
+	reflect.ValueOf(field.Pointer).Elem().Set(reflect.ValueOf(value))
+}
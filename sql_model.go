@@ -0,0 +1,1645 @@
+package surf
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// SqlModel is a database/sql implementation of a Model that
+// generates its queries through a Dialect
+type SqlModel struct {
+	Database Executor      `json:"-"`
+	Dialect  Dialect       `json:"-"`
+	Config   Configuration `json:"-"`
+}
+
+// SqlModel implements every optional interface of a Model
+var _ fullModel = (*SqlModel)(nil)
+
+// engine returns the sqlEngine that runs the queries for the model
+func (w *SqlModel) engine() sqlEngine {
+	return sqlEngine{Database: w.Database, Dialect: w.Dialect}
+}
+
+// GetConfiguration returns the configuration for the model
+func (w *SqlModel) GetConfiguration() *Configuration {
+	return &w.Config
+}
+
+// Insert inserts the model into the database
+func (w *SqlModel) Insert() error {
+	return w.InsertContext(context.Background())
+}
+
+// InsertContext inserts the model into the database
+func (w *SqlModel) InsertContext(ctx context.Context) error {
+	return w.engine().insert(ctx, w)
+}
+
+// BulkInsert inserts an array of models into the database
+func (w *SqlModel) BulkInsert(models []Model) error {
+	return w.BulkInsertContext(context.Background(), models)
+}
+
+// BulkInsertContext inserts an array of models into the database
+func (w *SqlModel) BulkInsertContext(ctx context.Context, models []Model) error {
+	return w.engine().bulkInsert(ctx, w, models)
+}
+
+// Upsert inserts the model into the database, or resolves a
+// conflict with an existing row
+func (w *SqlModel) Upsert(upsertConfig UpsertConfig) error {
+	return w.UpsertContext(context.Background(), upsertConfig)
+}
+
+// UpsertContext inserts the model into the database, or resolves a
+// conflict with an existing row
+func (w *SqlModel) UpsertContext(ctx context.Context, upsertConfig UpsertConfig) error {
+	return w.engine().upsert(ctx, w, upsertConfig)
+}
+
+// Load loads the model from the database from its unique identifier
+// and then loads those values into the struct
+func (w *SqlModel) Load() error {
+	return w.LoadContext(context.Background())
+}
+
+// LoadContext loads the model from the database from its unique identifier
+// and then loads those values into the struct
+func (w *SqlModel) LoadContext(ctx context.Context) error {
+	return w.engine().load(ctx, w, nil)
+}
+
+// LoadFields loads the fields of names of the model from the database
+// from its unique identifier, leaving its other fields untouched
+func (w *SqlModel) LoadFields(names []string) error {
+	return w.LoadFieldsContext(context.Background(), names)
+}
+
+// LoadFieldsContext loads the fields of names of the model from the database
+// from its unique identifier, leaving its other fields untouched
+func (w *SqlModel) LoadFieldsContext(ctx context.Context, names []string) error {
+	return w.engine().load(ctx, w, names)
+}
+
+// Update updates the model with the current values in the struct
+func (w *SqlModel) Update() error {
+	return w.UpdateContext(context.Background())
+}
+
+// UpdateContext updates the model with the current values in the struct
+func (w *SqlModel) UpdateContext(ctx context.Context) error {
+	return w.engine().update(ctx, w)
+}
+
+// BulkUpdate sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *SqlModel) BulkUpdate(predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.BulkUpdateContext(context.Background(), predicates, values)
+}
+
+// BulkUpdateContext sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *SqlModel) BulkUpdateContext(ctx context.Context, predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.engine().bulkUpdate(ctx, w, predicates, values)
+}
+
+// Delete deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *SqlModel) Delete() error {
+	return w.DeleteContext(context.Background())
+}
+
+// DeleteContext deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *SqlModel) DeleteContext(ctx context.Context) error {
+	return w.engine().delete(ctx, w)
+}
+
+// HardDelete deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *SqlModel) HardDelete() error {
+	return w.HardDeleteContext(context.Background())
+}
+
+// HardDeleteContext deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *SqlModel) HardDeleteContext(ctx context.Context) error {
+	return w.engine().hardDelete(ctx, w)
+}
+
+// Restore undoes the soft delete of the model
+func (w *SqlModel) Restore() error {
+	return w.RestoreContext(context.Background())
+}
+
+// RestoreContext undoes the soft delete of the model
+func (w *SqlModel) RestoreContext(ctx context.Context) error {
+	return w.engine().restore(ctx, w)
+}
+
+// BulkDelete deletes all rows that match predicates, or soft deletes them
+// in the event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *SqlModel) BulkDelete(predicates []Predicate) (int64, error) {
+	return w.BulkDeleteContext(context.Background(), predicates)
+}
+
+// BulkDeleteContext deletes all rows that match predicates, or soft deletes
+// them in the event that it has a DeletedAtField, returning the number of
+// rows that were deleted
+func (w *SqlModel) BulkDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	return w.engine().bulkDelete(ctx, w, predicates)
+}
+
+// BulkHardDelete deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *SqlModel) BulkHardDelete(predicates []Predicate) (int64, error) {
+	return w.BulkHardDeleteContext(context.Background(), predicates)
+}
+
+// BulkHardDeleteContext deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows that
+// were deleted
+func (w *SqlModel) BulkHardDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	return w.engine().bulkHardDelete(ctx, w, predicates)
+}
+
+// BulkFetch gets an array of models
+func (w *SqlModel) BulkFetch(fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	return w.BulkFetchContext(context.Background(), fetchConfig, buildModel)
+}
+
+// BulkFetchContext gets an array of models
+func (w *SqlModel) BulkFetchContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	return w.engine().bulkFetch(ctx, w, fetchConfig, buildModel)
+}
+
+// Stream calls handle with each of the models of a bulk fetch,
+// as the rows are read from the database
+func (w *SqlModel) Stream(fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.StreamContext(context.Background(), fetchConfig, buildModel, streamConfig, handle)
+}
+
+// StreamContext calls handle with each of the models of a bulk fetch,
+// as the rows are read from the database
+//
+// The rows are closed before StreamContext returns.  In the event handle
+// returns an error, the stream is stopped and the error is returned.
+func (w *SqlModel) StreamContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.engine().stream(ctx, w, fetchConfig, buildModel, streamConfig, handle)
+}
+
+// Count returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *SqlModel) Count(fetchConfig BulkFetchConfig) (int64, error) {
+	return w.CountContext(context.Background(), fetchConfig)
+}
+
+// CountContext returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *SqlModel) CountContext(ctx context.Context, fetchConfig BulkFetchConfig) (int64, error) {
+	return w.engine().count(ctx, w, fetchConfig)
+}
+
+// Exists returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *SqlModel) Exists(fetchConfig BulkFetchConfig) (bool, error) {
+	return w.ExistsContext(context.Background(), fetchConfig)
+}
+
+// ExistsContext returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *SqlModel) ExistsContext(ctx context.Context, fetchConfig BulkFetchConfig) (bool, error) {
+	return w.engine().exists(ctx, w, fetchConfig)
+}
+
+// Aggregate runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *SqlModel) Aggregate(aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.AggregateContext(context.Background(), aggregateConfig)
+}
+
+// AggregateContext runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *SqlModel) AggregateContext(ctx context.Context, aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.engine().aggregate(ctx, w, aggregateConfig)
+}
+
+// sqlEngine generates and executes the queries of a Model through a Dialect.
+//
+// It holds all of the logic shared by SqlModel and the database specific models
+type sqlEngine struct {
+	Database Executor
+	Dialect  Dialect
+}
+
+// columns writes a comma separated list of the quoted names of fields,
+// with each name being prefixed by prefix
+func (e sqlEngine) columns(queryBuffer *bytes.Buffer, prefix string, fields []Field) {
+	for i, field := range fields {
+		queryBuffer.WriteString(prefix)
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(field.Name))
+		if (i + 1) < len(fields) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+}
+
+// insert inserts the model into the database
+//
+// In the event the Dialect has no way of returning the inserted row, the first
+// UniqueIdentifier that is not Insertable is assumed to be auto incrementing,
+// and is set to the LastInsertId of the insert before the row is re-selected.
+func (e sqlEngine) insert(ctx context.Context, w Model) error {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+	returning := e.Dialect.Returning()
+
+	// Get Insertable Fields
+	var insertableFields []Field
+	for _, field := range config.Fields {
+		if field.Insertable {
+			insertableFields = append(insertableFields, field)
+		}
+	}
+
+	// Generate Query
+	query := e.insertQuery(config, insertableFields, "")
+
+	// Get Value Fields
+	var valueFields []interface{}
+	for _, value := range insertableFields {
+		valueFields = append(valueFields, value.Pointer)
+	}
+
+	// Log Query
+	printQuery(e.Dialect, query, valueFields...)
+
+	// Execute Query, re-selecting the row if it can't be returned
+	if returning == RETURNING_NONE {
+		res, err := db.ExecContext(ctx, query, valueFields...)
+		if err != nil {
+			return err
+		}
+
+		// Set the auto incrementing field
+		err = setLastInsertId(config, res)
+		if err != nil {
+			return err
+		}
+		err = e.loadRow(ctx, db, w, config.Fields, nil)
+		if err != nil {
+			return err
+		}
+		return expandForeign(ctx, w)
+	}
+	row := db.QueryRowContext(ctx, query, valueFields...)
+	err := consumeRow(w, row)
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeign(ctx, w)
+}
+
+// insertQuery generates the query that inserts the insertableFields of a
+// single row, where conflictClause is written before any RETURNING clause
+func (e sqlEngine) insertQuery(config *Configuration, insertableFields []Field, conflictClause string) string {
+	returning := e.Dialect.Returning()
+
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("INSERT INTO ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString("(")
+	e.columns(&queryBuffer, "", insertableFields)
+	queryBuffer.WriteString(")")
+	if returning == RETURNING_OUTPUT {
+		queryBuffer.WriteString(" OUTPUT ")
+		e.columns(&queryBuffer, "INSERTED.", config.Fields)
+	}
+	queryBuffer.WriteString(" VALUES(")
+	for i := range insertableFields {
+		queryBuffer.WriteString(e.Dialect.Placeholder(i + 1))
+		if (i + 1) < len(insertableFields) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	queryBuffer.WriteString(")")
+	if conflictClause != "" {
+		queryBuffer.WriteString(" ")
+		queryBuffer.WriteString(conflictClause)
+	}
+	if returning == RETURNING_CLAUSE {
+		queryBuffer.WriteString(" RETURNING ")
+		e.columns(&queryBuffer, "", config.Fields)
+	}
+	queryBuffer.WriteString(";")
+	return queryBuffer.String()
+}
+
+// upsert inserts the model into the database, or resolves a conflict
+// with an existing row as configured by upsertConfig
+//
+// In the event the Dialect has no way of returning the written row, or
+// the conflict was resolved by doing nothing, the row is re-selected by
+// the values of its conflict fields.
+func (e sqlEngine) upsert(ctx context.Context, w Model, upsertConfig UpsertConfig) error {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+
+	// Get Conflict Fields
+	conflictFields, err := upsertConfig.conflictFields(config)
+	if err != nil {
+		return err
+	}
+
+	// Get Insertable + Update Fields
+	var insertableFields []Field
+	var updateFields []string
+	for _, field := range config.Fields {
+		if field.Insertable {
+			insertableFields = append(insertableFields, field)
+			if field.Updatable && upsertConfig.Action == UPSERT_DO_UPDATE && !containsField(conflictFields, field.Name) {
+				updateFields = append(updateFields, field.Name)
+			}
+		}
+	}
+
+	// Get Value Fields
+	var valueFields []interface{}
+	for _, value := range insertableFields {
+		valueFields = append(valueFields, value.Pointer)
+	}
+
+	// Generate Query, incrementing the Version field of an updated row
+	conflictClause, err := e.Dialect.Upsert(fieldNames(conflictFields), updateFields)
+	if err != nil {
+		return err
+	}
+	if version, ok := versionField(config); ok && len(updateFields) > 0 && !containsString(updateFields, version.Name) {
+		var versionBuffer bytes.Buffer
+		versionBuffer.WriteString(conflictClause)
+		versionBuffer.WriteString(", ")
+		valueFields = append(valueFields, e.versionAssignment(&versionBuffer, version, len(valueFields)+1)...)
+		conflictClause = versionBuffer.String()
+	}
+	query := e.insertQuery(config, insertableFields, conflictClause)
+
+	// Log Query
+	printQuery(e.Dialect, query, valueFields...)
+
+	// Execute Query, re-selecting the row if it can't be returned
+	if e.Dialect.Returning() == RETURNING_NONE {
+		_, err = db.ExecContext(ctx, query, valueFields...)
+		if err != nil {
+			return err
+		}
+		err = e.loadRowByFields(ctx, db, w, conflictFields)
+	} else {
+		row := db.QueryRowContext(ctx, query, valueFields...)
+		err = consumeRow(w, row)
+		if err == sql.ErrNoRows {
+			err = e.loadRowByFields(ctx, db, w, conflictFields)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeign(ctx, w)
+}
+
+// versionAssignment writes the assignment that increments the Version field
+// to queryBuffer, returning the value that it binds at valueIndex, if any
+func (e sqlEngine) versionAssignment(queryBuffer *bytes.Buffer, version Field, valueIndex int) []interface{} {
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(version.Name))
+	queryBuffer.WriteString("=")
+	if integerVersion(version) {
+		queryBuffer.WriteString("COALESCE(")
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(version.Name))
+		queryBuffer.WriteString(", 0) + 1")
+		return nil
+	}
+	queryBuffer.WriteString(e.Dialect.Placeholder(valueIndex))
+	return []interface{}{nextVersion(version)}
+}
+
+// validatePredicates returns an error in the event that any of the
+// predicates filters by a column that isn't a field of config, or has
+// an operator that the Dialect doesn't have
+func (e sqlEngine) validatePredicates(config *Configuration, predicates []Predicate) error {
+	err := validatePredicates(config, predicates)
+	if err != nil {
+		return err
+	}
+	return supportPredicates(e.Dialect, config, predicates)
+}
+
+// loadRowByFields loads the values of the model from the database from the
+// values of fields, without expanding any foreign references
+func (e sqlEngine) loadRowByFields(ctx context.Context, db Executor, w Model, fields []Field) error {
+	config := w.GetConfiguration()
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("SELECT ")
+	e.columns(&queryBuffer, "", config.Fields)
+	queryBuffer.WriteString(" FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString(" WHERE ")
+	for i, field := range fields {
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(field.Name))
+		queryBuffer.WriteString("=")
+		queryBuffer.WriteString(e.Dialect.Placeholder(i + 1))
+		if (i + 1) < len(fields) {
+			queryBuffer.WriteString(" AND ")
+		}
+	}
+	queryBuffer.WriteString(";")
+
+	// Get Value Fields
+	var valueFields []interface{}
+	for _, field := range fields {
+		valueFields = append(valueFields, field.Pointer)
+	}
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, valueFields...)
+
+	// Execute Query
+	row := db.QueryRowContext(ctx, query, valueFields...)
+	return consumeRow(w, row)
+}
+
+// bulkInsert inserts models into the database through multi-row INSERTs,
+// each holding as many rows as the Dialect allows, and then expands the
+// foreign references of all of the models at once.
+//
+// The returned rows are scanned back into models in order.  In the event
+// the Dialect has no way of returning the inserted rows, they are re-selected
+// by the values of their first Insertable UniqueIdentifier.  Without one, each
+// row is inserted on its own, and re-selected by its auto incrementing value.
+func (e sqlEngine) bulkInsert(ctx context.Context, w Model, models []Model) error {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+
+	// If there's nothing to insert, exit early
+	if len(models) == 0 {
+		return nil
+	}
+
+	// Get Insertable Fields
+	var insertableFields []Field
+	for _, field := range config.Fields {
+		if field.Insertable {
+			insertableFields = append(insertableFields, field)
+		}
+	}
+	if len(insertableFields) == 0 {
+		return fmt.Errorf("Could not bulk insert into table '%v', as it has no Insertable fields",
+			config.TableName)
+	}
+
+	// Insert each batch
+	batchSize := e.Dialect.InsertBatchSize(len(insertableFields))
+	if _, ok := insertableUniqueIdentifier(config); !ok && e.Dialect.Returning() == RETURNING_NONE {
+		batchSize = 1
+	}
+	for start := 0; start < len(models); start += batchSize {
+		end := start + batchSize
+		if end > len(models) {
+			end = len(models)
+		}
+		err := e.insertBatch(ctx, db, config, insertableFields, models[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	// Expand foreign references
+	return expandForeigns(ctx, func() Model { return w }, models)
+}
+
+// insertBatch inserts models into the database through a single multi-row INSERT
+func (e sqlEngine) insertBatch(ctx context.Context, db Executor, config *Configuration, insertableFields []Field, models []Model) error {
+	returning := e.Dialect.Returning()
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("INSERT INTO ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString("(")
+	e.columns(&queryBuffer, "", insertableFields)
+	queryBuffer.WriteString(")")
+	if returning == RETURNING_OUTPUT {
+		queryBuffer.WriteString(" OUTPUT ")
+		e.columns(&queryBuffer, "INSERTED.", config.Fields)
+	}
+	queryBuffer.WriteString(" VALUES")
+	valueIndex := 1
+	for i := range models {
+		queryBuffer.WriteString("(")
+		for j := range insertableFields {
+			queryBuffer.WriteString(e.Dialect.Placeholder(valueIndex))
+			valueIndex++
+			if (j + 1) < len(insertableFields) {
+				queryBuffer.WriteString(", ")
+			}
+		}
+		queryBuffer.WriteString(")")
+		if (i + 1) < len(models) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	if returning == RETURNING_CLAUSE {
+		queryBuffer.WriteString(" RETURNING ")
+		e.columns(&queryBuffer, "", config.Fields)
+	}
+	queryBuffer.WriteString(";")
+
+	// Get Value Fields
+	var valueFields []interface{}
+	for _, model := range models {
+		for _, field := range model.GetConfiguration().Fields {
+			if field.Insertable {
+				valueFields = append(valueFields, field.Pointer)
+			}
+		}
+	}
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, valueFields...)
+
+	// Execute Query, re-selecting the rows if they can't be returned
+	if returning == RETURNING_NONE {
+		res, err := db.ExecContext(ctx, query, valueFields...)
+		if err != nil {
+			return err
+		}
+		return e.reselectBatch(ctx, db, config, res, models)
+	}
+	rows, err := db.QueryContext(ctx, query, valueFields...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanRows(rows, models)
+}
+
+// reselectBatch loads models that were inserted through a single INSERT
+// with the result res.
+//
+// A single row is re-selected by the LAST_INSERT_ID() of res.  Many rows are
+// re-selected by the values of their first Insertable UniqueIdentifier, as
+// the auto incrementing values of a multi-row INSERT may not be consecutive.
+func (e sqlEngine) reselectBatch(ctx context.Context, db Executor, config *Configuration, res sql.Result, models []Model) error {
+	if len(models) == 1 {
+		err := setLastInsertId(models[0].GetConfiguration(), res)
+		if err != nil {
+			return err
+		}
+		return e.loadRow(ctx, db, models[0], models[0].GetConfiguration().Fields, nil)
+	}
+
+	// Index the models by their unique values
+	uniqueIndex, _ := insertableUniqueIdentifier(config)
+	modelsByUnique := make(map[interface{}]Model, len(models))
+	var uniqueValues []interface{}
+	for _, model := range models {
+		uniqueValue := snapshotValue(model.GetConfiguration().Fields[uniqueIndex].Pointer)
+		modelsByUnique[uniqueValue] = model
+		uniqueValues = append(uniqueValues, uniqueValue)
+	}
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("SELECT ")
+	e.columns(&queryBuffer, "", config.Fields)
+	queryBuffer.WriteString(" FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString(" WHERE ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.Fields[uniqueIndex].Name))
+	queryBuffer.WriteString(" IN (")
+	for i := range uniqueValues {
+		queryBuffer.WriteString(e.Dialect.Placeholder(i + 1))
+		if (i + 1) < len(uniqueValues) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	queryBuffer.WriteString(");")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, uniqueValues...)
+
+	// Execute Query
+	rows, err := db.QueryContext(ctx, query, uniqueValues...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Scan each row into the model of its unique value
+	found := 0
+	for rows.Next() {
+		values := make([]interface{}, len(config.Fields))
+		for i, field := range config.Fields {
+			values[i] = reflect.New(reflect.TypeOf(field.Pointer).Elem()).Interface()
+		}
+		err := rows.Scan(values...)
+		if err != nil {
+			return err
+		}
+		model, ok := modelsByUnique[reflect.ValueOf(values[uniqueIndex]).Elem().Interface()]
+		if !ok {
+			return errors.New("A row was returned that was not inserted")
+		}
+		fields := model.GetConfiguration().Fields
+		for i, field := range fields {
+			setFieldValue(field, reflect.ValueOf(values[i]).Elem().Interface())
+		}
+		model.GetConfiguration().setLoaded(fields)
+		found++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if found != len(models) {
+		return fmt.Errorf("Expected %v rows to be returned, but got %v", len(models), found)
+	}
+	return nil
+}
+
+// load loads the model from the database from its unique identifier
+// and then loads those values into the struct
+//
+// Only the fields of names are loaded, unless names is empty.  A row that
+// is soft deleted is only loaded under WithDeleted or OnlyDeleted.
+func (e sqlEngine) load(ctx context.Context, w Model, names []string) error {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+	fields, err := config.projection(names)
+	if err != nil {
+		return err
+	}
+	err = e.loadRow(ctx, db, w, fields, config.scopeDeleted(ctx, nil))
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeign(ctx, w)
+}
+
+// loadRow loads the values of fields of the model from the database from its
+// unique identifier, without expanding any foreign references
+//
+// The row must also match all of predicates.
+func (e sqlEngine) loadRow(ctx context.Context, db Executor, w Model, fields []Field, predicates []Predicate) error {
+	config := w.GetConfiguration()
+
+	// Get Unique Identifier
+	uniqueIdentifierField, err := getUniqueIdentifier(w)
+	if err != nil {
+		return err
+	}
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("SELECT ")
+	e.columns(&queryBuffer, "", fields)
+	queryBuffer.WriteString(" FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString(" WHERE ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(uniqueIdentifierField.Name))
+	queryBuffer.WriteString("=")
+	queryBuffer.WriteString(e.Dialect.Placeholder(1))
+	values := []interface{}{uniqueIdentifierField.Pointer}
+	if len(predicates) > 0 {
+		predicatesStr, predicateValues := joinPredicates(e.Dialect, len(values)+1, " AND ", predicates)
+		values = append(values, predicateValues...)
+		queryBuffer.WriteString(" AND ")
+		queryBuffer.WriteString(predicatesStr)
+	}
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, values...)
+
+	// Execute Query
+	row := db.QueryRowContext(ctx, query, values...)
+	return consumeRowFields(w, row, fields)
+}
+
+// update updates the model with the current values in the struct
+//
+// Only the Updatable fields that have changed since the model was last read
+// are set, and the update is skipped in the event that none have changed.
+//
+// In the event the model has a Version field, only the row of the version of
+// the model is updated, and its version is incremented.  ErrStaleObject is
+// returned in the event that no row has the version.
+//
+// In the event the Dialect has no way of returning the updated row,
+// the row is re-selected.
+func (e sqlEngine) update(ctx context.Context, w Model) error {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+	returning := e.Dialect.Returning()
+
+	// Get Unique Identifier + Version
+	uniqueIdentifierField, err := getUniqueIdentifier(w)
+	if err != nil {
+		return err
+	}
+	version, versioned := versionField(config)
+
+	// Get changed updatable fields
+	var updatableFields []Field
+	for _, field := range config.Fields {
+		if field.Updatable && !field.Version && config.isChanged(field) {
+			updatableFields = append(updatableFields, field)
+		}
+	}
+
+	// If there's nothing to update, exit early
+	if len(updatableFields) == 0 {
+		return nil
+	}
+
+	// Get Value Fields
+	var valueFields []interface{}
+	for _, value := range updatableFields {
+		valueFields = append(valueFields, value.Pointer)
+	}
+	if versioned {
+		updatableFields = append(updatableFields, version)
+		valueFields = append(valueFields, nextVersion(version))
+	}
+	valueFields = append(valueFields, uniqueIdentifierField.Pointer)
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("UPDATE ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString(" SET ")
+	for i, field := range updatableFields {
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(field.Name))
+		queryBuffer.WriteString("=")
+		queryBuffer.WriteString(e.Dialect.Placeholder(i + 1))
+		if (i + 1) < len(updatableFields) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	if returning == RETURNING_OUTPUT {
+		queryBuffer.WriteString(" OUTPUT ")
+		e.columns(&queryBuffer, "INSERTED.", config.Fields)
+	}
+	queryBuffer.WriteString(" WHERE ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(uniqueIdentifierField.Name))
+	queryBuffer.WriteString("=")
+	queryBuffer.WriteString(e.Dialect.Placeholder(len(valueFields)))
+	if versioned {
+		valueFields = append(valueFields, version.Pointer)
+		queryBuffer.WriteString(" AND ")
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(version.Name))
+		queryBuffer.WriteString("=")
+		queryBuffer.WriteString(e.Dialect.Placeholder(len(valueFields)))
+	}
+	if returning == RETURNING_CLAUSE {
+		queryBuffer.WriteString(" RETURNING ")
+		e.columns(&queryBuffer, "", config.Fields)
+	}
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, valueFields...)
+
+	// Execute Query, re-selecting the row if it can't be returned
+	if returning == RETURNING_NONE {
+		res, err := db.ExecContext(ctx, query, valueFields...)
+		if err != nil {
+			return err
+		}
+		if versioned {
+			numRows, _ := res.RowsAffected()
+			if numRows == 0 {
+				return ErrStaleObject
+			}
+		}
+		err = e.loadRow(ctx, db, w, config.Fields, nil)
+		if err != nil {
+			return err
+		}
+		return expandForeign(ctx, w)
+	}
+	row := db.QueryRowContext(ctx, query, valueFields...)
+	err = consumeRow(w, row)
+	if err == sql.ErrNoRows && versioned {
+		return ErrStaleObject
+	}
+	if err != nil {
+		return err
+	}
+
+	// Expand foreign references
+	return expandForeign(ctx, w)
+}
+
+// delete deletes the model, or soft deletes it in the event that it
+// has a DeletedAtField
+func (e sqlEngine) delete(ctx context.Context, w Model) error {
+	if _, ok := w.GetConfiguration().deletedAtField(); ok {
+		deletedAt := time.Now().UTC().Truncate(time.Microsecond)
+		return e.setDeletedAt(ctx, w, &deletedAt)
+	}
+	return e.hardDelete(ctx, w)
+}
+
+// restore undoes the soft delete of the model
+func (e sqlEngine) restore(ctx context.Context, w Model) error {
+	if _, ok := w.GetConfiguration().deletedAtField(); !ok {
+		return fmt.Errorf("Could not restore a model of table '%v', as it has no DeletedAtField",
+			w.GetConfiguration().TableName)
+	}
+	return e.setDeletedAt(ctx, w, nil)
+}
+
+// setDeletedAt soft deletes the model at deletedAt, or restores it in the
+// event deletedAt is nil
+//
+// In the event the model has a Version field, only the row of the version of
+// the model is updated, and its version is incremented.  ErrStaleObject is
+// returned in the event that no row has the version.
+func (e sqlEngine) setDeletedAt(ctx context.Context, w Model, deletedAt *time.Time) error {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+
+	// Get Unique Identifier + Version
+	uniqueIdentifierField, err := getUniqueIdentifier(w)
+	if err != nil {
+		return err
+	}
+	version, versioned := versionField(config)
+
+	// Get Set Fields
+	deletedAtField, _ := config.deletedAtField()
+	setFields := []Field{deletedAtField}
+	setValues := []interface{}{deletedAtValue(deletedAtField, deletedAt)}
+	if versioned {
+		setFields = append(setFields, version)
+		setValues = append(setValues, nextVersion(version))
+	}
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("UPDATE ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString(" SET ")
+	for i, field := range setFields {
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(field.Name))
+		queryBuffer.WriteString("=")
+		queryBuffer.WriteString(e.Dialect.Placeholder(i + 1))
+		if (i + 1) < len(setFields) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	valueFields := append(setValues, uniqueIdentifierField.Pointer)
+	queryBuffer.WriteString(" WHERE ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(uniqueIdentifierField.Name))
+	queryBuffer.WriteString("=")
+	queryBuffer.WriteString(e.Dialect.Placeholder(len(valueFields)))
+	if versioned {
+		valueFields = append(valueFields, version.Pointer)
+		queryBuffer.WriteString(" AND ")
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(version.Name))
+		queryBuffer.WriteString("=")
+		queryBuffer.WriteString(e.Dialect.Placeholder(len(valueFields)))
+	}
+	deletedStr, _ := joinPredicates(e.Dialect, len(valueFields)+1, " AND ", []Predicate{config.deletedPredicate(deletedAt == nil)})
+	queryBuffer.WriteString(" AND ")
+	queryBuffer.WriteString(deletedStr)
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, valueFields...)
+
+	// Execute Query
+	res, err := db.ExecContext(ctx, query, valueFields...)
+	if err != nil {
+		return err
+	}
+	numRows, _ := res.RowsAffected()
+	if numRows != 1 {
+		if versioned {
+			return ErrStaleObject
+		}
+		if deletedAt == nil {
+			return errors.New("Nothing was restored")
+		}
+		return errors.New("Nothing was deleted")
+	}
+
+	// Set the values on the model
+	for i, field := range setFields {
+		setFieldValue(field, setValues[i])
+	}
+	config.refreshSnapshot(setFields)
+	return nil
+}
+
+// hardDelete deletes the row of the model
+//
+// In the event the model has a Version field, only the row of the version
+// of the model is deleted, and ErrStaleObject is returned in the event
+// that no row has the version.
+func (e sqlEngine) hardDelete(ctx context.Context, w Model) error {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+
+	// Get Unique Identifier + Version
+	uniqueIdentifierField, err := getUniqueIdentifier(w)
+	if err != nil {
+		return err
+	}
+	version, versioned := versionField(config)
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("DELETE FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString(" WHERE ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(uniqueIdentifierField.Name))
+	queryBuffer.WriteString("=")
+	queryBuffer.WriteString(e.Dialect.Placeholder(1))
+	valueFields := []interface{}{uniqueIdentifierField.Pointer}
+	if versioned {
+		valueFields = append(valueFields, version.Pointer)
+		queryBuffer.WriteString(" AND ")
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(version.Name))
+		queryBuffer.WriteString("=")
+		queryBuffer.WriteString(e.Dialect.Placeholder(2))
+	}
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, valueFields...)
+
+	// Execute Query
+	res, err := db.ExecContext(ctx, query, valueFields...)
+	if err != nil {
+		return err
+	}
+	numRows, _ := res.RowsAffected()
+	if numRows != 1 {
+		if versioned {
+			return ErrStaleObject
+		}
+		return errors.New("Nothing was deleted")
+	}
+	return nil
+}
+
+// bulkUpdate sets the fields of values on all rows that match predicates,
+// returning the number of rows that were updated
+//
+// In the event the model has a Version field that isn't in values, the
+// version of each row is incremented, so that models read before the
+// update are stale.
+func (e sqlEngine) bulkUpdate(ctx context.Context, w Model, predicates []Predicate, values map[string]interface{}) (int64, error) {
+	config := w.GetConfiguration()
+
+	// Validate predicates + values
+	if len(predicates) == 0 {
+		return 0, errors.New("BulkUpdate requires at least one predicate")
+	}
+	err := e.validatePredicates(config, predicates)
+	if err != nil {
+		return 0, err
+	}
+	updateFields, err := bulkUpdateFields(config, values)
+	if err != nil {
+		return 0, err
+	}
+	var updateValues []interface{}
+	for _, field := range updateFields {
+		updateValues = append(updateValues, values[field.Name])
+	}
+	return e.bulkSet(ctx, config, config.scopeDeleted(ctx, predicates), updateFields, updateValues)
+}
+
+// bulkDelete deletes all rows that match predicates, or soft deletes them in
+// the event that the model has a DeletedAtField, returning the number of
+// rows that were deleted
+func (e sqlEngine) bulkDelete(ctx context.Context, w Model, predicates []Predicate) (int64, error) {
+	config := w.GetConfiguration()
+	deletedAtField, ok := config.deletedAtField()
+	if !ok {
+		return e.bulkHardDelete(ctx, w, predicates)
+	}
+
+	// Validate predicates
+	if len(predicates) == 0 {
+		return 0, errors.New("BulkDelete requires at least one predicate")
+	}
+	err := e.validatePredicates(config, predicates)
+	if err != nil {
+		return 0, err
+	}
+
+	deletedAt := time.Now().UTC().Truncate(time.Microsecond)
+	return e.bulkSet(ctx, config, config.scopeUndeleted(ctx, predicates),
+		[]Field{deletedAtField}, []interface{}{deletedAtValue(deletedAtField, &deletedAt)})
+}
+
+// bulkSet sets fields to values on all rows that match predicates, returning
+// the number of rows that were updated
+//
+// In the event the model has a Version field that isn't one of fields, the
+// version of each row is incremented.
+func (e sqlEngine) bulkSet(ctx context.Context, config *Configuration, predicates []Predicate, fields []Field, values []interface{}) (int64, error) {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("UPDATE ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString(" SET ")
+	valueFields := append([]interface{}{}, values...)
+	for i, field := range fields {
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(field.Name))
+		queryBuffer.WriteString("=")
+		queryBuffer.WriteString(e.Dialect.Placeholder(i + 1))
+		if (i + 1) < len(fields) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	if version, ok := versionField(config); ok && !containsField(fields, version.Name) {
+		queryBuffer.WriteString(", ")
+		valueFields = append(valueFields, e.versionAssignment(&queryBuffer, version, len(valueFields)+1)...)
+	}
+	queryBuffer.WriteString(" ")
+	predicatesStr, predicateValues := predicatesToString(e.Dialect, len(valueFields)+1, predicates)
+	valueFields = append(valueFields, predicateValues...)
+	queryBuffer.WriteString(predicatesStr)
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, valueFields...)
+
+	// Execute Query
+	res, err := db.ExecContext(ctx, query, valueFields...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// bulkHardDelete deletes all rows that match predicates, even in the event
+// that the model has a DeletedAtField, returning the number of rows that
+// were deleted
+func (e sqlEngine) bulkHardDelete(ctx context.Context, w Model, predicates []Predicate) (int64, error) {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+
+	// Validate predicates
+	if len(predicates) == 0 {
+		return 0, errors.New("BulkDelete requires at least one predicate")
+	}
+	err := e.validatePredicates(config, predicates)
+	if err != nil {
+		return 0, err
+	}
+	predicates = config.scopeDeleted(ctx, predicates)
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("DELETE FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	queryBuffer.WriteString(" ")
+	predicatesStr, values := predicatesToString(e.Dialect, 1, predicates)
+	queryBuffer.WriteString(predicatesStr)
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, values...)
+
+	// Execute Query
+	res, err := db.ExecContext(ctx, query, values...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// count returns the number of rows that match the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (e sqlEngine) count(ctx context.Context, w Model, fetchConfig BulkFetchConfig) (int64, error) {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+	fetchConfig.Predicates = config.scopeDeleted(ctx, fetchConfig.Predicates)
+
+	// Validate predicates
+	err := e.validatePredicates(config, fetchConfig.Predicates)
+	if err != nil {
+		return 0, err
+	}
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("SELECT COUNT(*) FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	values := e.where(&queryBuffer, fetchConfig.Predicates)
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, values...)
+
+	// Execute Query
+	var count int64
+	err = db.QueryRowContext(ctx, query, values...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// exists returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (e sqlEngine) exists(ctx context.Context, w Model, fetchConfig BulkFetchConfig) (bool, error) {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+	fetchConfig.Predicates = config.scopeDeleted(ctx, fetchConfig.Predicates)
+
+	// Validate predicates
+	err := e.validatePredicates(config, fetchConfig.Predicates)
+	if err != nil {
+		return false, err
+	}
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("SELECT 1 FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	values := e.where(&queryBuffer, fetchConfig.Predicates)
+	queryBuffer.WriteString(e.Dialect.LimitOffset(1, 0, false))
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, values...)
+
+	// Execute Query
+	var one int
+	err = db.QueryRowContext(ctx, query, values...).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// aggregate runs the aggregates of aggregateConfig over the table, grouped
+// by its GroupBys
+func (e sqlEngine) aggregate(ctx context.Context, w Model, aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+	aggregateConfig.Predicates = config.scopeDeleted(ctx, aggregateConfig.Predicates)
+
+	// Validate
+	err := aggregateConfig.validate(config)
+	if err != nil {
+		return nil, err
+	}
+	err = supportPredicates(e.Dialect, config, append(append([]Predicate{}, aggregateConfig.Predicates...), aggregateConfig.Having...))
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate Query
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("SELECT ")
+	for _, groupBy := range aggregateConfig.GroupBys {
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(groupBy))
+		queryBuffer.WriteString(", ")
+	}
+	for i, aggregate := range aggregateConfig.Aggregates {
+		queryBuffer.WriteString(aggregate.toString(e.Dialect))
+		queryBuffer.WriteString(" AS ")
+		queryBuffer.WriteString(e.Dialect.QuoteIdentifier(aggregate.alias()))
+		if (i + 1) < len(aggregateConfig.Aggregates) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	queryBuffer.WriteString(" FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(config.TableName))
+	values := e.where(&queryBuffer, aggregateConfig.Predicates)
+	if len(aggregateConfig.GroupBys) > 0 {
+		queryBuffer.WriteString(" GROUP BY ")
+		for i, groupBy := range aggregateConfig.GroupBys {
+			queryBuffer.WriteString(e.Dialect.QuoteIdentifier(groupBy))
+			if (i + 1) < len(aggregateConfig.GroupBys) {
+				queryBuffer.WriteString(", ")
+			}
+		}
+	}
+	if len(aggregateConfig.Having) > 0 {
+		queryBuffer.WriteString(" HAVING ")
+		havingStr, havingValues := joinPredicates(e.Dialect, len(values)+1, " AND ", aggregateConfig.having(e.Dialect, aggregateConfig.Having))
+		values = append(values, havingValues...)
+		queryBuffer.WriteString(havingStr)
+	}
+	if len(aggregateConfig.OrderBys) > 0 {
+		queryBuffer.WriteString(" ORDER BY ")
+		for i, orderBy := range aggregateConfig.OrderBys {
+			queryBuffer.WriteString(aggregateConfig.orderByString(e.Dialect, orderBy))
+			if (i + 1) < len(aggregateConfig.OrderBys) {
+				queryBuffer.WriteString(", ")
+			}
+		}
+	}
+	if aggregateConfig.Limit > 0 {
+		queryBuffer.WriteString(e.Dialect.LimitOffset(aggregateConfig.Limit, aggregateConfig.Offset, len(aggregateConfig.OrderBys) > 0))
+	}
+	queryBuffer.WriteString(";")
+
+	// Log Query
+	query := queryBuffer.String()
+	printQuery(e.Dialect, query, values...)
+
+	// Execute Query
+	rows, err := db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Stuff into []AggregateRow
+	results := make([]AggregateRow, 0)
+	for rows.Next() {
+		var groups, aggregates []interface{}
+		for _, groupBy := range aggregateConfig.GroupBys {
+			groups = append(groups, aggregateTarget(config, groupBy))
+		}
+		for _, aggregate := range aggregateConfig.Aggregates {
+			switch aggregate.Function {
+			case AGGREGATE_COUNT, AGGREGATE_COUNT_DISTINCT:
+				aggregates = append(aggregates, new(int64))
+			case AGGREGATE_SUM, AGGREGATE_AVG:
+				aggregates = append(aggregates, new(sql.NullFloat64))
+			default:
+				aggregates = append(aggregates, aggregateTarget(config, aggregate.Field))
+			}
+		}
+		err := rows.Scan(append(groups, aggregates...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		result := AggregateRow{Groups: make(map[string]interface{}), Values: make(map[string]interface{})}
+		for i, groupBy := range aggregateConfig.GroupBys {
+			result.Groups[groupBy] = aggregateValue(groups[i])
+		}
+		for i, aggregate := range aggregateConfig.Aggregates {
+			result.Values[aggregate.alias()] = aggregateValue(aggregates[i])
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// where writes the WHERE clause of predicates, if there are any,
+// and returns the values to be passed along with the query
+func (e sqlEngine) where(queryBuffer *bytes.Buffer, predicates []Predicate) []interface{} {
+	if len(predicates) == 0 {
+		return nil
+	}
+	queryBuffer.WriteString(" ")
+	predicatesStr, values := predicatesToString(e.Dialect, 1, predicates)
+	queryBuffer.WriteString(predicatesStr)
+	return values
+}
+
+// bulkFetch gets an array of models
+func (e sqlEngine) bulkFetch(ctx context.Context, w Model, fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	ctx, _ = executor(ctx, e.Database, e.Dialect)
+
+	// Stuff into []Model
+	models := make([]Model, 0)
+	err := e.stream(ctx, w, fetchConfig, buildModel, StreamConfig{}, func(model Model) error {
+		models = append(models, model)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand foreign references
+	err = expandForeigns(ctx, buildModel, models)
+	if err != nil {
+		return nil, err
+	}
+
+	// OK
+	return models, nil
+}
+
+// fetchQuery generates the SELECT of a bulk fetch, along with its values
+// to be passed along with the query.
+//
+// In the event headline is set, its ts_headline is selected after the
+// columns of the fields.
+func (e sqlEngine) fetchQuery(config *Configuration, fetchConfig BulkFetchConfig, buildModel BuildModel, headline *TextSearch) (string, []interface{}, error) {
+	// Validate predicates
+	err := e.validatePredicates(config, fetchConfig.Predicates)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Set up values
+	values := make([]interface{}, 0)
+
+	// Set up keyset pagination
+	orderBys, offset := fetchConfig.OrderBys, fetchConfig.Offset
+	var cursorValues []interface{}
+	if fetchConfig.keyset() {
+		orderBys, cursorValues, err = fetchConfig.seek(config)
+		if err != nil {
+			return "", nil, err
+		}
+		offset = 0
+	}
+
+	// Generate query
+	fields, err := config.projection(fetchConfig.Fields)
+	if err != nil {
+		return "", nil, err
+	}
+	var queryBuffer bytes.Buffer
+	queryBuffer.WriteString("SELECT ")
+	e.columns(&queryBuffer, "", fields)
+	if headline != nil {
+		headlineStr, headlineValues := headline.headlineToString(e.Dialect, len(values)+1)
+
+		values = append(values, headlineValues...)
+		queryBuffer.WriteString(", ")
+		queryBuffer.WriteString(headlineStr)
+	}
+	queryBuffer.WriteString(" FROM ")
+	queryBuffer.WriteString(e.Dialect.QuoteIdentifier(buildModel().GetConfiguration().TableName))
+	if len(fetchConfig.Predicates) > 0 {
+		// WHERE
+		queryBuffer.WriteString(" ")
+		predicatesStr, predicateValues := predicatesToString(e.Dialect, len(values)+1, fetchConfig.Predicates)
+
+		values = append(values, predicateValues...)
+		queryBuffer.WriteString(predicatesStr)
+	}
+	if cursorValues != nil {
+		// Seek past the cursor
+		if len(fetchConfig.Predicates) > 0 {
+			queryBuffer.WriteString(" AND ")
+		} else {
+			queryBuffer.WriteString(" WHERE ")
+		}
+		seekStr, seekValues := seekToString(e.Dialect, len(values)+1, orderBys, cursorValues)
+
+		values = append(values, seekValues...)
+		queryBuffer.WriteString(seekStr)
+	}
+	if len(orderBys) > 0 {
+		queryBuffer.WriteString(" ORDER BY ")
+	}
+	for i, orderBy := range orderBys {
+		// Validate that the orderBy.Field is a field, or an expression
+		expression := ""
+		if containsField(config.Fields, orderBy.Field) {
+			expression = e.Dialect.QuoteIdentifier(orderBy.Field)
+		} else if orderExpression, ok := config.OrderExpressions[orderBy.Field]; ok {
+			expression = orderExpression
+		} else {
+			return "", nil, invalidColumn("order", config, orderBy.Field)
+		}
+
+		// Write to query
+		if orderBy.Rank != nil {
+			rankStr, rankValues := orderBy.Rank.rankToString(e.Dialect, len(values)+1)
+
+			values = append(values, rankValues...)
+			expression = rankStr
+		}
+		queryBuffer.WriteString(orderBy.expressionToString(e.Dialect, expression))
+		if (i + 1) < len(orderBys) {
+			queryBuffer.WriteString(", ")
+		}
+	}
+	queryBuffer.WriteString(e.Dialect.LimitOffset(fetchConfig.Limit, offset, len(orderBys) > 0))
+	queryBuffer.WriteString(";")
+	return queryBuffer.String(), values, nil
+}
+
+// stream calls handle with each of the models of a bulk fetch, as the rows
+// are read from the database
+//
+// In the event that foreign references are expanded, the models are read in
+// pages of BatchSize rows, and the rows of each page are closed before the
+// foreign references of its models are expanded, as most drivers can't run
+// another query on a connection while its rows are being read.
+func (e sqlEngine) stream(ctx context.Context, w Model, fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+	fetchConfig.Predicates = config.scopeDeleted(ctx, fetchConfig.Predicates)
+	if streamConfig.ExpandForeigns {
+		return e.streamPages(ctx, db, config, fetchConfig, buildModel, streamConfig.batchSize(), handle)
+	}
+	return e.streamRows(ctx, db, config, fetchConfig, buildModel, handle)
+}
+
+// streamRows calls handle with each of the models of a bulk fetch, as the
+// rows are read from db
+func (e sqlEngine) streamRows(ctx context.Context, db Executor, config *Configuration, fetchConfig BulkFetchConfig, buildModel BuildModel, handle func(Model) error) error {
+	// Generate Query
+	query, values, err := e.fetchQuery(config, fetchConfig, buildModel, nil)
+	if err != nil {
+		return err
+	}
+
+	// Log Query
+	printQuery(e.Dialect, query, values...)
+
+	// Execute Query
+	rows, err := db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Stream each row into a model
+	for rows.Next() {
+		model := buildModel()
+
+		// Consume Rows
+		err := consumeRows(model, rows, fetchConfig.Fields)
+		if err != nil {
+			return err
+		}
+
+		err = handle(model)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// streamPages calls handle with each of the models of a bulk fetch, after
+// their foreign references are expanded, reading pages of batchSize models
+// from db until the Limit of fetchConfig is reached
+//
+// Each page after the first seeks past the last model of the page before it,
+// as a Keyset fetch does.  Only a fetch that can't be paged with a cursor,
+// such as one ordered by an expression or by the rank of a search, is paged
+// with an Offset instead.
+func (e sqlEngine) streamPages(ctx context.Context, db Executor, config *Configuration, fetchConfig BulkFetchConfig, buildModel BuildModel, batchSize int, handle func(Model) error) error {
+	page := fetchConfig
+	seek := page.keyset()
+	if !seek {
+		orderBys, err := page.keysetOrderBys(config)
+		if err == nil {
+			page.OrderBys, seek = orderBys, true
+		} else {
+			page.OrderBys = page.pageOrderBys(config)
+		}
+	}
+	for remaining := fetchConfig.Limit; remaining > 0; {
+		page.Limit = batchSize
+		if remaining < batchSize {
+			page.Limit = remaining
+		}
+
+		// Read the page, closing its rows
+		var models []Model
+		err := e.streamRows(ctx, db, config, page, buildModel, func(model Model) error {
+			models = append(models, model)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Expand the foreign references of the page, then handle it
+		err = expandForeigns(ctx, buildModel, models)
+		if err != nil {
+			return err
+		}
+		for _, model := range models {
+			err := handle(model)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Move past the page
+		if len(models) < page.Limit {
+			return nil
+		}
+		remaining -= len(models)
+		if seek {
+			page.Keyset, page.Offset = true, 0
+			page.Cursor, err = page.NextCursor(models[len(models)-1])
+			if err != nil {
+				return err
+			}
+		} else {
+			page.Offset += len(models)
+		}
+	}
+	return nil
+}
+
+// search gets the models of a bulk fetch that match a full-text search,
+// ordered by their relevance to the search
+func (e sqlEngine) search(ctx context.Context, w Model, fetchConfig BulkFetchConfig, search TextSearch, buildModel BuildModel) ([]SearchResult, error) {
+	ctx, db := executor(ctx, e.Database, e.Dialect)
+	config := w.GetConfiguration()
+
+	// Match + order by the search
+	fetchConfig.Predicates = config.scopeDeleted(ctx, append([]Predicate{search.Predicate()}, fetchConfig.Predicates...))
+	fetchConfig.OrderBys = append([]OrderBy{search.OrderBy()}, fetchConfig.OrderBys...)
+	var headline *TextSearch
+	if search.Headline {
+		headline = &search
+	}
+
+	// Generate Query
+	query, values, err := e.fetchQuery(config, fetchConfig, buildModel, headline)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log Query
+	printQuery(e.Dialect, query, values...)
+
+	// Execute Query
+	rows, err := db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Stuff into []SearchResult
+	results := make([]SearchResult, 0)
+	models := make([]Model, 0)
+	for rows.Next() {
+		model := buildModel()
+		result := SearchResult{Model: model}
+
+		// Consume Rows
+		var extra []interface{}
+		if headline != nil {
+			extra = append(extra, &result.Headline)
+		}
+		err := consumeRows(model, rows, fetchConfig.Fields, extra...)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+		models = append(models, model)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Expand foreign references
+	err = expandForeigns(ctx, buildModel, models)
+	if err != nil {
+		return nil, err
+	}
+
+	// OK
+	return results, nil
+}
@@ -0,0 +1,232 @@
+package surf
+
+import (
+	"context"
+)
+
+// SqliteModel is a SQLite implementation of a Model, written against
+// database/sql so that any SQLite driver (such as github.com/mattn/go-sqlite3)
+// may be used.
+//
+// Insert and Update rely on the RETURNING clause, so SQLite 3.35 or later is
+// required.  SQLite stores values by the type affinity of their column, so
+// columns should be declared with the affinity (INTEGER, REAL, TEXT, ...) that
+// matches the type of the Field.Pointer they are scanned back into.
+type SqliteModel struct {
+	Database Executor      `json:"-"`
+	Config   Configuration `json:"-"`
+}
+
+// SqliteModel implements every optional interface of a Model
+var _ fullModel = (*SqliteModel)(nil)
+
+// engine returns the sqlEngine that runs the queries for the model
+func (w *SqliteModel) engine() sqlEngine {
+	return sqlEngine{Database: w.Database, Dialect: SqliteDialect{}}
+}
+
+// GetConfiguration returns the configuration for the model
+func (w *SqliteModel) GetConfiguration() *Configuration {
+	return &w.Config
+}
+
+// Insert inserts the model into the database
+func (w *SqliteModel) Insert() error {
+	return w.InsertContext(context.Background())
+}
+
+// InsertContext inserts the model into the database
+func (w *SqliteModel) InsertContext(ctx context.Context) error {
+	return w.engine().insert(ctx, w)
+}
+
+// BulkInsert inserts an array of models into the database
+func (w *SqliteModel) BulkInsert(models []Model) error {
+	return w.BulkInsertContext(context.Background(), models)
+}
+
+// BulkInsertContext inserts an array of models into the database
+func (w *SqliteModel) BulkInsertContext(ctx context.Context, models []Model) error {
+	return w.engine().bulkInsert(ctx, w, models)
+}
+
+// Upsert inserts the model into the database, or resolves a
+// conflict with an existing row
+func (w *SqliteModel) Upsert(upsertConfig UpsertConfig) error {
+	return w.UpsertContext(context.Background(), upsertConfig)
+}
+
+// UpsertContext inserts the model into the database, or resolves a
+// conflict with an existing row
+func (w *SqliteModel) UpsertContext(ctx context.Context, upsertConfig UpsertConfig) error {
+	return w.engine().upsert(ctx, w, upsertConfig)
+}
+
+// Load loads the model from the database from its unique identifier
+// and then loads those values into the struct
+func (w *SqliteModel) Load() error {
+	return w.LoadContext(context.Background())
+}
+
+// LoadContext loads the model from the database from its unique identifier
+// and then loads those values into the struct
+func (w *SqliteModel) LoadContext(ctx context.Context) error {
+	return w.engine().load(ctx, w, nil)
+}
+
+// LoadFields loads the fields of names of the model from the database
+// from its unique identifier, leaving its other fields untouched
+func (w *SqliteModel) LoadFields(names []string) error {
+	return w.LoadFieldsContext(context.Background(), names)
+}
+
+// LoadFieldsContext loads the fields of names of the model from the database
+// from its unique identifier, leaving its other fields untouched
+func (w *SqliteModel) LoadFieldsContext(ctx context.Context, names []string) error {
+	return w.engine().load(ctx, w, names)
+}
+
+// Update updates the model with the current values in the struct
+func (w *SqliteModel) Update() error {
+	return w.UpdateContext(context.Background())
+}
+
+// UpdateContext updates the model with the current values in the struct
+func (w *SqliteModel) UpdateContext(ctx context.Context) error {
+	return w.engine().update(ctx, w)
+}
+
+// BulkUpdate sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *SqliteModel) BulkUpdate(predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.BulkUpdateContext(context.Background(), predicates, values)
+}
+
+// BulkUpdateContext sets the fields of values on all rows that match
+// predicates, returning the number of rows that were updated
+func (w *SqliteModel) BulkUpdateContext(ctx context.Context, predicates []Predicate, values map[string]interface{}) (int64, error) {
+	return w.engine().bulkUpdate(ctx, w, predicates, values)
+}
+
+// Delete deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *SqliteModel) Delete() error {
+	return w.DeleteContext(context.Background())
+}
+
+// DeleteContext deletes the model, or soft deletes it in the event
+// that it has a DeletedAtField
+func (w *SqliteModel) DeleteContext(ctx context.Context) error {
+	return w.engine().delete(ctx, w)
+}
+
+// HardDelete deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *SqliteModel) HardDelete() error {
+	return w.HardDeleteContext(context.Background())
+}
+
+// HardDeleteContext deletes the row of the model, even in the event
+// that it has a DeletedAtField
+func (w *SqliteModel) HardDeleteContext(ctx context.Context) error {
+	return w.engine().hardDelete(ctx, w)
+}
+
+// Restore undoes the soft delete of the model
+func (w *SqliteModel) Restore() error {
+	return w.RestoreContext(context.Background())
+}
+
+// RestoreContext undoes the soft delete of the model
+func (w *SqliteModel) RestoreContext(ctx context.Context) error {
+	return w.engine().restore(ctx, w)
+}
+
+// BulkDelete deletes all rows that match predicates, or soft deletes them
+// in the event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *SqliteModel) BulkDelete(predicates []Predicate) (int64, error) {
+	return w.BulkDeleteContext(context.Background(), predicates)
+}
+
+// BulkDeleteContext deletes all rows that match predicates, or soft deletes
+// them in the event that it has a DeletedAtField, returning the number of
+// rows that were deleted
+func (w *SqliteModel) BulkDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	return w.engine().bulkDelete(ctx, w, predicates)
+}
+
+// BulkHardDelete deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows
+// that were deleted
+func (w *SqliteModel) BulkHardDelete(predicates []Predicate) (int64, error) {
+	return w.BulkHardDeleteContext(context.Background(), predicates)
+}
+
+// BulkHardDeleteContext deletes all rows that match predicates, even in the
+// event that it has a DeletedAtField, returning the number of rows that
+// were deleted
+func (w *SqliteModel) BulkHardDeleteContext(ctx context.Context, predicates []Predicate) (int64, error) {
+	return w.engine().bulkHardDelete(ctx, w, predicates)
+}
+
+// BulkFetch gets an array of models
+func (w *SqliteModel) BulkFetch(fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	return w.BulkFetchContext(context.Background(), fetchConfig, buildModel)
+}
+
+// BulkFetchContext gets an array of models
+func (w *SqliteModel) BulkFetchContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel) ([]Model, error) {
+	return w.engine().bulkFetch(ctx, w, fetchConfig, buildModel)
+}
+
+// Stream calls handle with each of the models of a bulk fetch,
+// as the rows are read from the database
+func (w *SqliteModel) Stream(fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.StreamContext(context.Background(), fetchConfig, buildModel, streamConfig, handle)
+}
+
+// StreamContext calls handle with each of the models of a bulk fetch,
+// as the rows are read from the database
+//
+// The rows are closed before StreamContext returns.  In the event handle
+// returns an error, the stream is stopped and the error is returned.
+func (w *SqliteModel) StreamContext(ctx context.Context, fetchConfig BulkFetchConfig, buildModel BuildModel, streamConfig StreamConfig, handle func(Model) error) error {
+	return w.engine().stream(ctx, w, fetchConfig, buildModel, streamConfig, handle)
+}
+
+// Count returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *SqliteModel) Count(fetchConfig BulkFetchConfig) (int64, error) {
+	return w.CountContext(context.Background(), fetchConfig)
+}
+
+// CountContext returns the number of rows that match the predicates
+// of fetchConfig, ignoring its ordering and paging
+func (w *SqliteModel) CountContext(ctx context.Context, fetchConfig BulkFetchConfig) (int64, error) {
+	return w.engine().count(ctx, w, fetchConfig)
+}
+
+// Exists returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *SqliteModel) Exists(fetchConfig BulkFetchConfig) (bool, error) {
+	return w.ExistsContext(context.Background(), fetchConfig)
+}
+
+// ExistsContext returns if any row matches the predicates of fetchConfig,
+// ignoring its ordering and paging
+func (w *SqliteModel) ExistsContext(ctx context.Context, fetchConfig BulkFetchConfig) (bool, error) {
+	return w.engine().exists(ctx, w, fetchConfig)
+}
+
+// Aggregate runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *SqliteModel) Aggregate(aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.AggregateContext(context.Background(), aggregateConfig)
+}
+
+// AggregateContext runs the aggregates of aggregateConfig over the table,
+// returning a row for each group
+func (w *SqliteModel) AggregateContext(ctx context.Context, aggregateConfig AggregateConfig) ([]AggregateRow, error) {
+	return w.engine().aggregate(ctx, w, aggregateConfig)
+}
@@ -0,0 +1,662 @@
+package surf_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-carrot/surf"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/guregu/null.v3"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// sqliteModels returns a modelBuilder that builds a surf.SqliteModel
+func sqliteModels(dbConnection surf.Executor) modelBuilder {
+	return func(config surf.Configuration) surf.Model {
+		return &surf.SqliteModel{Database: dbConnection, Config: config}
+	}
+}
+
+// sqlModels returns a function that creates a modelBuilder that
+// builds a surf.SqlModel with the given dialect
+func sqlModels(dialect surf.Dialect) func(surf.Executor) modelBuilder {
+	return func(dbConnection surf.Executor) modelBuilder {
+		return func(config surf.Configuration) surf.Model {
+			return &surf.SqlModel{Database: dbConnection, Dialect: dialect, Config: config}
+		}
+	}
+}
+
+// reselectDialect is a surf.SqliteDialect that has no way of returning the
+// rows that it writes, as with MySQL, so every write is re-selected
+type reselectDialect struct {
+	surf.SqliteDialect
+}
+
+func (reselectDialect) Returning() surf.ReturningType {
+	return surf.RETURNING_NONE
+}
+
+// recordedModel is a surf.Model that is also a surf.ContextModel
+type recordedModel interface {
+	surf.Model
+	surf.ContextModel
+}
+
+// contextRecorder is a surf.ContextModel that records the context of every
+// LoadContext and BulkFetchContext call made on it
+type contextRecorder struct {
+	recordedModel
+	contexts *[]context.Context
+}
+
+func (r contextRecorder) LoadContext(ctx context.Context) error {
+	*r.contexts = append(*r.contexts, ctx)
+	return r.recordedModel.LoadContext(ctx)
+}
+
+func (r contextRecorder) BulkFetchContext(ctx context.Context, fetchConfig surf.BulkFetchConfig, buildModel surf.BuildModel) ([]surf.Model, error) {
+	*r.contexts = append(*r.contexts, ctx)
+	return r.recordedModel.BulkFetchContext(ctx, fetchConfig, buildModel)
+}
+
+// ================================
+// ========== Test Suite ==========
+// ================================
+
+type SqliteModelTestSuite struct {
+	ModelTestSuite
+	db      *sql.DB
+	builder func(surf.Executor) modelBuilder
+}
+
+func (suite *SqliteModelTestSuite) SetupTest() {
+	// Enable logging
+	stackWriter := &StackWriter{}
+	surf.SetLogging(true, stackWriter)
+
+	// Opening + storing the connection.  Every connection to `:memory:` is
+	// its own database, so the pool is limited to a single connection.
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		suite.Fail("Failed to open database connection")
+	}
+	db.SetMaxOpenConns(1)
+
+	// Creating the tables
+	_, err = db.Exec(`
+		CREATE TABLE animals(
+			id    INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug  TEXT    UNIQUE NOT NULL,
+			name  TEXT    NOT NULL,
+			age   INTEGER NOT NULL
+		);
+		CREATE TABLE toys(
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			name         TEXT    NOT NULL,
+			owner        INTEGER NOT NULL REFERENCES animals(id) ON DELETE CASCADE,
+			second_owner INTEGER REFERENCES animals(id)
+		);
+		CREATE TABLE notes(
+			id      INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug    TEXT    UNIQUE,
+			body    TEXT    NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1
+		);
+		CREATE TABLE comments(
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			body       TEXT    NOT NULL,
+			reply_to   INTEGER REFERENCES comments(id) ON DELETE SET NULL,
+			deleted_at DATETIME
+		);`)
+	if err != nil {
+		suite.Fail("Failed to create tables")
+	}
+
+	suite.db = db
+	suite.models = suite.builder(db)
+}
+
+func (suite *SqliteModelTestSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+func (suite *SqliteModelTestSuite) TestInsert() {
+	// Create an Animal
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	err := rigby.Insert()
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), int64(0), rigby.Id)
+
+	// Cause a conflict to test errors are being thrown
+	rigbyTwo := NewAnimalWith(suite.models)
+	rigbyTwo.Name = "Rigby Two"
+	rigbyTwo.Slug = "rigby" // This should cause a conflict
+	rigbyTwo.Age = 3
+	err = rigbyTwo.Insert()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *SqliteModelTestSuite) TestBulkInsert() {
+	// Insert enough animals to need more than one INSERT
+	var animals []surf.Model
+	for i := 0; i < 12000; i++ {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = "Animal"
+		animal.Slug = "animal-" + strconv.Itoa(i)
+		animal.Age = i
+		animals = append(animals, animal)
+	}
+	err := NewAnimalWith(suite.models).Model.(surf.BulkInserter).BulkInsert(animals)
+	assert.Nil(suite.T(), err)
+	for i, animal := range animals {
+		assert.Equal(suite.T(), int64(i+1), animal.(*Animal).Id)
+	}
+
+	// Verify they were inserted
+	animalVerification := NewAnimalWith(suite.models)
+	animalVerification.Slug = "animal-11999"
+	err = animalVerification.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(12000), animalVerification.Id)
+	assert.Equal(suite.T(), 11999, animalVerification.Age)
+
+	// Insert toys, which should expand their owners
+	var toys []surf.Model
+	for i := 0; i < 3; i++ {
+		toy := NewToyWith(suite.models)
+		toy.Name = "toy"
+		toy.OwnerId = int64(i + 1)
+		if i == 0 {
+			toy.SecondOwnerId = null.IntFrom(3)
+		}
+		toys = append(toys, toy)
+	}
+	err = NewToyWith(suite.models).Model.(surf.BulkInserter).BulkInsert(toys)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "animal-0", toys[0].(*Toy).Owner.Slug)
+	assert.Equal(suite.T(), "animal-2", toys[0].(*Toy).SecondOwner.Slug)
+	assert.Equal(suite.T(), "animal-2", toys[2].(*Toy).Owner.Slug)
+	assert.Nil(suite.T(), toys[1].(*Toy).SecondOwner)
+
+	// Inserting nothing does nothing
+	err = NewToyWith(suite.models).Model.(surf.BulkInserter).BulkInsert(nil)
+	assert.Nil(suite.T(), err)
+
+	// Cause a conflict on a UniqueIdentifier
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigbyTwo := NewAnimalWith(suite.models)
+	rigbyTwo.Name = "Rigby Two"
+	rigbyTwo.Slug = "rigby"
+	err = NewAnimalWith(suite.models).Model.(surf.BulkInserter).BulkInsert([]surf.Model{rigby, rigbyTwo})
+	assert.NotNil(suite.T(), err)
+	assert.False(suite.T(), suite.animalExists("rigby"))
+}
+
+func (suite *SqliteModelTestSuite) TestLoad() {
+	// Create an Animal
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	rigby.Insert()
+
+	// Verify it loads from id
+	rigbyIdLoad := NewAnimalWith(suite.models)
+	rigbyIdLoad.Id = rigby.Id
+	err := rigbyIdLoad.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), rigby.Slug, rigbyIdLoad.Slug)
+	assert.Equal(suite.T(), 3, rigbyIdLoad.Age)
+
+	// Verify it loads from slug
+	rigbySlugLoad := NewAnimalWith(suite.models)
+	rigbySlugLoad.Slug = rigby.Slug
+	err = rigbySlugLoad.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), rigby.Id, rigbySlugLoad.Id)
+
+	// Make sure an error is thrown when nothing is set
+	err = NewAnimalWith(suite.models).Load()
+	assert.NotNil(suite.T(), err)
+
+	// Make sure an error is thrown when trying to load something that doesn't exist
+	dummyAnimal := NewAnimalWith(suite.models)
+	dummyAnimal.Slug = "wow-cool-cat"
+	err = dummyAnimal.Load()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *SqliteModelTestSuite) TestUpdate() {
+	// Create an Animal
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	rigby.Insert()
+
+	// Update
+	rigby.Age = 4
+	err := rigby.Update()
+	assert.Nil(suite.T(), err)
+
+	// Verify the update happened in the DB
+	rigbyVerification := NewAnimalWith(suite.models)
+	rigbyVerification.Id = rigby.Id
+	rigbyVerification.Load()
+	assert.Equal(suite.T(), 4, rigbyVerification.Age)
+
+	// Update something that doesn't exist
+	dummyAnimal := NewAnimalWith(suite.models)
+	dummyAnimal.Slug = "wow-cool-cat"
+	err = dummyAnimal.Update()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *SqliteModelTestSuite) TestStreamInTx() {
+	suite.streamInTx(suite.db, suite.builder)
+}
+
+func (suite *SqliteModelTestSuite) TestAggregateNullsEmulation() {
+	stackWriter := &StackWriter{}
+	surf.SetLogging(true, stackWriter)
+
+	// A surf.MssqlDialect has no NULLS LAST, so the aggregate is written
+	// into its CASE, rather than the alias
+	NewAnimalWith(sqlModels(surf.MssqlDialect{})(suite.db)).Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		GroupBys:   []string{"name"},
+		Aggregates: []surf.Aggregate{{Function: surf.AGGREGATE_MAX, Field: "age"}},
+		OrderBys:   []surf.OrderBy{{Field: "max_age", Type: surf.ORDER_BY_DESC, Nulls: surf.NULLS_LAST}},
+	})
+	assert.Contains(suite.T(), stackWriter.Peek(), "ORDER BY CASE WHEN MAX([age]) IS NULL THEN 1 ELSE 0 END, MAX([age]) DESC")
+}
+
+func (suite *SqliteModelTestSuite) TestIdentifiers() {
+	// Create a table of reserved word + mixed case columns
+	_, err := suite.db.Exec(`CREATE TABLE "Order"("Id" INTEGER PRIMARY KEY AUTOINCREMENT, "Group" TEXT NOT NULL, "ItemCount" INTEGER NOT NULL);`)
+	assert.Nil(suite.T(), err)
+
+	// newOrder returns a model of the table
+	type order struct {
+		surf.Model
+		Id        int64
+		Group     string
+		ItemCount int
+	}
+	newOrder := func() *order {
+		o := new(order)
+		o.Model = suite.models(surf.Configuration{
+			TableName: "Order",
+			Fields: []surf.Field{
+				{Pointer: &o.Id, Name: "Id", UniqueIdentifier: true, IsSet: func(pointer interface{}) bool {
+					return *pointer.(*int64) != 0
+				}},
+				{Pointer: &o.Group, Name: "Group", Insertable: true, Updatable: true},
+				{Pointer: &o.ItemCount, Name: "ItemCount", Insertable: true, Updatable: true},
+			},
+		})
+		return o
+	}
+
+	// Insert + fetch
+	for i, group := range []string{"a", "b", "a"} {
+		o := newOrder()
+		o.Group = group
+		o.ItemCount = i + 1
+		err := o.Insert()
+		assert.Nil(suite.T(), err)
+	}
+	models, err := newOrder().BulkFetch(surf.BulkFetchConfig{
+		Limit:      10,
+		Predicates: []surf.Predicate{{Field: "Group", PredicateType: surf.WHERE_EQUAL, Values: []interface{}{"a"}}},
+		OrderBys:   []surf.OrderBy{{Field: "ItemCount", Type: surf.ORDER_BY_DESC}},
+	}, func() surf.Model {
+		return newOrder()
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(models))
+	assert.Equal(suite.T(), 3, models[0].(*order).ItemCount)
+
+	// Aggregate
+	rows, err := newOrder().Model.(surf.Aggregator).Aggregate(surf.AggregateConfig{
+		Aggregates: []surf.Aggregate{{Function: surf.AGGREGATE_SUM, Field: "ItemCount"}},
+		GroupBys:   []string{"Group"},
+		Having:     []surf.Predicate{{Field: "sum_ItemCount", PredicateType: surf.WHERE_GREATER_THAN, Values: []interface{}{2}}},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(rows))
+	assert.Equal(suite.T(), "a", rows[0].Groups["Group"])
+
+	// Unknown fields are an InvalidColumnError
+	predicates := []surf.Predicate{{Field: "1 = 1 OR name", PredicateType: surf.WHERE_IS_NOT_NULL}}
+	buildAnimal := func() surf.Model {
+		return NewAnimalWith(suite.models)
+	}
+	var invalidColumnErr *surf.InvalidColumnError
+	_, err = NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{Predicates: predicates}, buildAnimal)
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	assert.Equal(suite.T(), "animals", invalidColumnErr.TableName)
+	assert.Equal(suite.T(), "1 = 1 OR name", invalidColumnErr.Column)
+	assert.Equal(suite.T(), "filter", invalidColumnErr.Action)
+	_, err = NewAnimalWith(suite.models).Model.(surf.Counter).Count(surf.BulkFetchConfig{Predicates: predicates})
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	_, err = NewAnimalWith(suite.models).Model.(surf.Counter).Exists(surf.BulkFetchConfig{Predicates: []surf.Predicate{surf.Or(predicates...)}})
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	_, err = NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+		OrderBys: []surf.OrderBy{{Field: "age; DROP TABLE animals"}},
+	}, buildAnimal)
+	assert.True(suite.T(), errors.As(err, &invalidColumnErr))
+	assert.Equal(suite.T(), "order", invalidColumnErr.Action)
+}
+
+func (suite *SqliteModelTestSuite) TestDelete() {
+	// Create an Animal
+	rigby := NewAnimalWith(suite.models)
+	rigby.Name = "Rigby"
+	rigby.Slug = "rigby"
+	rigby.Age = 3
+	rigby.Insert()
+
+	// Delete
+	err := rigby.Delete()
+	assert.Nil(suite.T(), err)
+
+	// Try to delete an animal that doesn't exist
+	err = rigby.Delete()
+	assert.NotNil(suite.T(), err)
+
+	// Make sure we can't delete without a unique set
+	err = NewAnimalWith(suite.models).Delete()
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *SqliteModelTestSuite) TestBulkFetch() {
+	// Create some Animals
+	for _, name := range []string{"Luna", "Rae", "Rigby"} {
+		animal := NewAnimalWith(suite.models)
+		animal.Name = name
+		animal.Slug = name
+		animal.Age = len(name)
+		animal.Insert()
+	}
+
+	// Predicates
+	config := surf.BulkFetchConfig{
+		Limit: 10,
+		Predicates: []surf.Predicate{
+			{Field: "age", PredicateType: surf.WHERE_LESS_THAN, Values: []interface{}{5}},
+			{Field: "name", PredicateType: surf.WHERE_NOT_IN, Values: []interface{}{"Luna"}},
+		},
+	}
+	config.ConsumeSortQuery("-name")
+	animals, err := NewAnimalWith(suite.models).BulkFetch(config, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(animals))
+	assert.Equal(suite.T(), "Rae", animals[0].(*Animal).Name)
+
+	// Limit + Offset
+	animals, err = NewAnimalWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+		Limit:    2,
+		Offset:   1,
+		OrderBys: []surf.OrderBy{{Field: "name", Type: surf.ORDER_BY_ASC}},
+	}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(animals))
+	assert.Equal(suite.T(), "Rae", animals[0].(*Animal).Name)
+	assert.Equal(suite.T(), "Rigby", animals[1].(*Animal).Name)
+
+	// Invalid order by
+	config.ConsumeSortQuery("helloworld")
+	_, err = NewAnimalWith(suite.models).BulkFetch(config, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *SqliteModelTestSuite) TestNestedModel() {
+	// Create an Animal
+	cat := NewAnimalWith(suite.models)
+	cat.Name = "Luna"
+	cat.Slug = "luna"
+	cat.Age = 2
+	cat.Insert()
+
+	// Create toys, which should expand their owners
+	tennisBall := NewToyWith(suite.models)
+	tennisBall.Name = "tennis ball"
+	tennisBall.OwnerId = cat.Id
+	err := tennisBall.Insert()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), cat.Id, tennisBall.Owner.Id)
+	assert.Nil(suite.T(), tennisBall.SecondOwner)
+
+	sock := NewToyWith(suite.models)
+	sock.Name = "sock"
+	sock.OwnerId = cat.Id
+	sock.SecondOwnerId = null.IntFrom(cat.Id)
+	err = sock.Insert()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), cat.Id, sock.SecondOwner.Id)
+
+	// Load all toys
+	toys, err := NewToyWith(suite.models).BulkFetch(surf.BulkFetchConfig{
+		Limit: 10,
+		OrderBys: []surf.OrderBy{
+			{Field: "id", Type: surf.ORDER_BY_ASC},
+		},
+	}, func() surf.Model {
+		return NewToyWith(suite.models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(toys))
+	assert.Equal(suite.T(), "Luna", toys[0].(*Toy).Owner.Name)
+	assert.Nil(suite.T(), toys[0].(*Toy).SecondOwner)
+	assert.Equal(suite.T(), "Luna", toys[1].(*Toy).SecondOwner.Name)
+}
+
+func (suite *SqliteModelTestSuite) TestContext() {
+	// Create an Animal
+	cat := NewAnimalWith(suite.models)
+	cat.Name = "Luna"
+	cat.Slug = "luna"
+	cat.Age = 2
+	err := cat.Model.(surf.ContextModel).InsertContext(context.Background())
+	assert.Nil(suite.T(), err)
+
+	// Create a toy through a builder that records contexts
+	var contexts []context.Context
+	models := func(config surf.Configuration) surf.Model {
+		return contextRecorder{recordedModel: suite.models(config).(recordedModel), contexts: &contexts}
+	}
+	tennisBall := NewToyWith(models)
+	tennisBall.Name = "tennis ball"
+	tennisBall.OwnerId = cat.Id
+	err = tennisBall.Model.(surf.ContextModel).InsertContext(context.Background())
+	assert.Nil(suite.T(), err)
+
+	// The context should flow into the foreign reference expansion
+	type contextKey struct{}
+	ctx := context.WithValue(context.Background(), contextKey{}, "toys")
+	contexts = nil
+	_, err = NewToyWith(models).Model.(surf.ContextModel).BulkFetchContext(ctx, surf.BulkFetchConfig{Limit: 10}, func() surf.Model {
+		return NewToyWith(models)
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(contexts))
+	for _, recorded := range contexts {
+		assert.Equal(suite.T(), "toys", recorded.Value(contextKey{}))
+	}
+
+	contexts = nil
+	err = tennisBall.Model.(surf.ContextModel).LoadContext(ctx)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(contexts))
+	for _, recorded := range contexts {
+		assert.Equal(suite.T(), "toys", recorded.Value(contextKey{}))
+	}
+
+	// Nothing should run with a cancelled context
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cat.Age = 3
+	assert.Equal(suite.T(), context.Canceled, NewAnimalWith(suite.models).Model.(surf.ContextModel).InsertContext(cancelledCtx))
+	assert.Equal(suite.T(), context.Canceled, cat.Model.(surf.ContextModel).LoadContext(cancelledCtx))
+	assert.Equal(suite.T(), context.Canceled, cat.Model.(surf.ContextModel).UpdateContext(cancelledCtx))
+	assert.Equal(suite.T(), context.Canceled, cat.Model.(surf.ContextModel).DeleteContext(cancelledCtx))
+	_, err = NewAnimalWith(suite.models).Model.(surf.ContextModel).BulkFetchContext(cancelledCtx, surf.BulkFetchConfig{Limit: 10}, func() surf.Model {
+		return NewAnimalWith(suite.models)
+	})
+	assert.Equal(suite.T(), context.Canceled, err)
+
+	// Verify nothing changed
+	catVerification := NewAnimalWith(suite.models)
+	catVerification.Id = cat.Id
+	err = catVerification.Load()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, catVerification.Age)
+}
+
+// animalExists returns if an Animal with the slug exists
+func (suite *SqliteModelTestSuite) animalExists(slug string) bool {
+	animal := NewAnimalWith(suite.models)
+	animal.Slug = slug
+	return animal.Load() == nil
+}
+
+func (suite *SqliteModelTestSuite) TestTransaction() {
+	insertAnimal := func(tx *surf.Tx, slug string) error {
+		animal := NewAnimalWith(suite.builder(tx))
+		animal.Name = slug
+		animal.Slug = slug
+		return animal.Insert()
+	}
+
+	// Commit
+	err := surf.WithTx(suite.db, func(tx *surf.Tx) error {
+		return insertAnimal(tx, "rigby")
+	})
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.animalExists("rigby"))
+
+	// Rollback on an error
+	err = surf.WithTx(suite.db, func(tx *surf.Tx) error {
+		insertAnimal(tx, "luna")
+		return errors.New("Something went wrong")
+	})
+	assert.Equal(suite.T(), "Something went wrong", err.Error())
+	assert.False(suite.T(), suite.animalExists("luna"))
+
+	// Rollback on a panic
+	assert.Panics(suite.T(), func() {
+		surf.WithTx(suite.db, func(tx *surf.Tx) error {
+			insertAnimal(tx, "luna")
+			panic("Something went wrong")
+		})
+	})
+	assert.False(suite.T(), suite.animalExists("luna"))
+
+	// Nested transactions only roll back their savepoint
+	err = surf.WithTx(suite.db, func(tx *surf.Tx) error {
+		insertAnimal(tx, "outer")
+		err := surf.WithTx(tx, func(nestedTx *surf.Tx) error {
+			insertAnimal(nestedTx, "inner")
+			return errors.New("Something went wrong")
+		})
+		assert.NotNil(suite.T(), err)
+		return surf.WithTx(tx, func(nestedTx *surf.Tx) error {
+			return surf.WithTx(nestedTx, func(nestedNestedTx *surf.Tx) error {
+				return insertAnimal(nestedNestedTx, "inner-ok")
+			})
+		})
+	})
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.animalExists("outer"))
+	assert.False(suite.T(), suite.animalExists("inner"))
+	assert.True(suite.T(), suite.animalExists("inner-ok"))
+
+	// A *sql.Tx may also be used, which creates a savepoint
+	sqlTx, err := suite.db.Begin()
+	assert.Nil(suite.T(), err)
+	err = surf.WithTx(sqlTx, func(tx *surf.Tx) error {
+		return insertAnimal(tx, "norbert")
+	})
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), sqlTx.Rollback())
+	assert.False(suite.T(), suite.animalExists("norbert"))
+
+	// Anything else is an error
+	err = surf.WithTx(nil, func(tx *surf.Tx) error {
+		return nil
+	})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *SqliteModelTestSuite) TestTransactionNestedModel() {
+	// Toys are written through the transaction, but their owners are built
+	// on the *sql.DB.  As the pool is limited to a single connection, the
+	// owners could only be loaded if they use the same transaction.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := surf.WithTxContext(ctx, suite.db, func(tx *surf.Tx) error {
+		txModels := suite.builder(tx)
+		models := func(config surf.Configuration) surf.Model {
+			if config.TableName == "toys" {
+				return txModels(config)
+			}
+			return suite.models(config)
+		}
+
+		cat := NewAnimalWith(txModels)
+		cat.Name = "Luna"
+		cat.Slug = "luna"
+		err := cat.Model.(surf.ContextModel).InsertContext(ctx)
+		assert.Nil(suite.T(), err)
+
+		tennisBall := NewToyWith(models)
+		tennisBall.Name = "tennis ball"
+		tennisBall.OwnerId = cat.Id
+		err = tennisBall.Model.(surf.ContextModel).InsertContext(ctx)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), "Luna", tennisBall.Owner.Name)
+
+		toys, err := NewToyWith(models).Model.(surf.ContextModel).BulkFetchContext(ctx, surf.BulkFetchConfig{Limit: 10}, func() surf.Model {
+			return NewToyWith(models)
+		})
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), 1, len(toys))
+		assert.Equal(suite.T(), "Luna", toys[0].(*Toy).Owner.Name)
+		return err
+	})
+	assert.Nil(suite.T(), err)
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestSqliteModelTestSuite(t *testing.T) {
+	suite.Run(t, &SqliteModelTestSuite{ModelTestSuite: ModelTestSuite{distinctFrom: true}, builder: sqliteModels})
+}
+
+// The suite is run a second time against a surf.SqlModel to
+// test the generic model with a surf.SqliteDialect
+func TestSqlModelTestSuite(t *testing.T) {
+	suite.Run(t, &SqliteModelTestSuite{ModelTestSuite: ModelTestSuite{distinctFrom: true}, builder: sqlModels(surf.SqliteDialect{})})
+}
+
+// The suite is run a third time against a surf.SqlModel that
+// re-selects every row that it writes
+func TestSqlModelReselectTestSuite(t *testing.T) {
+	suite.Run(t, &SqliteModelTestSuite{ModelTestSuite: ModelTestSuite{distinctFrom: true}, builder: sqlModels(reselectDialect{})})
+}
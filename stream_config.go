@@ -0,0 +1,100 @@
+package surf
+
+import (
+	"context"
+)
+
+// StreamConfig is the configuration of a Model.Stream()
+type StreamConfig struct {
+	// ExpandForeigns expands the foreign references of the models before
+	// they are handled, in batches of BatchSize models.  A SQL model reads
+	// each batch with its own query, whose rows are closed before the
+	// foreign references are expanded.
+	ExpandForeigns bool
+
+	// BatchSize is the number of models whose foreign references are
+	// expanded together, which defaults to 100
+	BatchSize int
+}
+
+// batchSize returns the BatchSize of the StreamConfig, or its default
+func (c StreamConfig) batchSize() int {
+	if c.BatchSize > 0 {
+		return c.BatchSize
+	}
+	return 100
+}
+
+// pageOrderBys returns the OrderBys of the BulkFetchConfig, followed by the
+// first UniqueIdentifier field of config, unless the OrderBys already contain
+// a UniqueIdentifier field, so that rows are in the same order on each page
+func (c BulkFetchConfig) pageOrderBys(config *Configuration) []OrderBy {
+	var tiebreaker *Field
+	for i, field := range config.Fields {
+		if !field.UniqueIdentifier {
+			continue
+		}
+		for _, orderBy := range c.OrderBys {
+			if orderBy.Field == field.Name {
+				return c.OrderBys
+			}
+		}
+		if tiebreaker == nil {
+			tiebreaker = &config.Fields[i]
+		}
+	}
+	if tiebreaker == nil {
+		return c.OrderBys
+	}
+	return append(append([]OrderBy{}, c.OrderBys...), OrderBy{Field: tiebreaker.Name, Type: ORDER_BY_ASC})
+}
+
+// streamBatch holds the models of a stream until their foreign
+// references are expanded, and they can be handled
+type streamBatch struct {
+	ctx          context.Context
+	streamConfig StreamConfig
+	buildModel   BuildModel
+	handle       func(Model) error
+	models       []Model
+}
+
+// batch returns a streamBatch that passes models along to handle
+func (c StreamConfig) batch(ctx context.Context, buildModel BuildModel, handle func(Model) error) *streamBatch {
+	return &streamBatch{ctx: ctx, streamConfig: c, buildModel: buildModel, handle: handle}
+}
+
+// add handles model, or holds it until the batch is full in the
+// event that foreign references are expanded
+func (b *streamBatch) add(model Model) error {
+	if !b.streamConfig.ExpandForeigns {
+		return b.handle(model)
+	}
+	b.models = append(b.models, model)
+	if len(b.models) >= b.streamConfig.batchSize() {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush expands the foreign references of the held models,
+// and then handles them
+func (b *streamBatch) flush() error {
+	if len(b.models) == 0 {
+		return nil
+	}
+	models := b.models
+	b.models = nil
+
+	err := expandForeigns(b.ctx, b.buildModel, models)
+	if err != nil {
+		return err
+	}
+	for _, model := range models {
+		err := b.handle(model)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package surf
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TextSearch is the definition of a Postgres full-text search of a field,
+// with a query in the format of `websearch_to_tsquery`
+type TextSearch struct {
+	Field string
+	Query string
+
+	// Config is the text search configuration of the search, such as
+	// `english`, which defaults to the `default_text_search_config`
+	Config string
+
+	// Headline selects a `ts_headline` snippet of the matching text of
+	// each model, which is returned in SearchResult.Headline
+	Headline bool
+
+	// HeadlineOptions are the options of the `ts_headline`, such as
+	// `MaxWords=20, MinWords=5`
+	HeadlineOptions string
+}
+
+// SearchResult is a model returned by a full-text search, along with
+// the headline of its matching text
+type SearchResult struct {
+	Model    Model
+	Headline string
+}
+
+// Predicate returns the WHERE_MATCHES predicate of the search
+func (s TextSearch) Predicate() Predicate {
+	if s.Config == "" {
+		return Predicate{Field: s.Field, PredicateType: WHERE_MATCHES, Values: []interface{}{s.Query}}
+	}
+	return Predicate{Field: s.Field, PredicateType: WHERE_MATCHES, Values: []interface{}{s.Config, s.Query}}
+}
+
+// OrderBy returns an OrderBy of the relevance of the search, with the
+// most relevant rows first
+func (s TextSearch) OrderBy() OrderBy {
+	return OrderBy{Field: s.Field, Type: ORDER_BY_DESC, Rank: &s}
+}
+
+// config converts the Config of the search to the first argument of a
+// text search function, along with its values to be passed along with the query
+func (s *TextSearch) config(dialect Dialect, valueIndex int) (string, []interface{}) {
+	if s.Config == "" {
+		return "", []interface{}{}
+	}
+	return dialect.Placeholder(valueIndex) + "::regconfig, ", []interface{}{s.Config}
+}
+
+// vectorToString converts the Field of the search to its `to_tsvector`,
+// along with its values to be passed along with the query
+func (s *TextSearch) vectorToString(dialect Dialect, valueIndex int) (string, []interface{}) {
+	config, values := s.config(dialect, valueIndex)
+	return "to_tsvector(" + config + dialect.QuoteIdentifier(s.Field) + ")", values
+}
+
+// queryToString converts the Query of the search to its `websearch_to_tsquery`,
+// along with its values to be passed along with the query
+func (s *TextSearch) queryToString(dialect Dialect, valueIndex int) (string, []interface{}) {
+	config, values := s.config(dialect, valueIndex)
+	values = append(values, s.Query)
+	return "websearch_to_tsquery(" + config + dialect.Placeholder(valueIndex+len(values)-1) + ")", values
+}
+
+// matchesToString converts the search to its `@@` predicate, along with its
+// values to be passed along with the query
+func (s *TextSearch) matchesToString(dialect Dialect, valueIndex int) (string, []interface{}) {
+	vector, values := s.vectorToString(dialect, valueIndex)
+	query, queryValues := s.queryToString(dialect, valueIndex+len(values))
+	return vector + " @@ " + query, append(values, queryValues...)
+}
+
+// rankToString converts the search to its `ts_rank`, along with its
+// values to be passed along with the query
+func (s *TextSearch) rankToString(dialect Dialect, valueIndex int) (string, []interface{}) {
+	vector, values := s.vectorToString(dialect, valueIndex)
+	query, queryValues := s.queryToString(dialect, valueIndex+len(values))
+	return "ts_rank(" + vector + ", " + query + ")", append(values, queryValues...)
+}
+
+// headlineToString converts the search to its `ts_headline`, along with its
+// values to be passed along with the query
+func (s *TextSearch) headlineToString(dialect Dialect, valueIndex int) (string, []interface{}) {
+	config, values := s.config(dialect, valueIndex)
+	query, queryValues := s.queryToString(dialect, valueIndex+len(values))
+	values = append(values, queryValues...)
+	headline := "ts_headline(" + config + dialect.QuoteIdentifier(s.Field) + ", " + query
+	if s.HeadlineOptions != "" {
+		headline += ", " + dialect.Placeholder(valueIndex+len(values))
+		values = append(values, s.HeadlineOptions)
+	}
+	return headline + ")", values
+}
+
+// textSearch returns the TextSearch of a WHERE_MATCHES predicate
+func (p *Predicate) textSearch() TextSearch {
+	if len(p.Values) == 2 {
+		return TextSearch{Field: p.Field, Config: p.Values[0].(string), Query: p.Values[1].(string)}
+	}
+	return TextSearch{Field: p.Field, Query: p.Values[0].(string)}
+}
+
+// textSearchWords splits text into its lowercase words
+func textSearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// textSearchTerms parses the Query of a search into the alternatives that are
+// separated by `or`, each with the words that must match and the words that
+// must not match.
+//
+// This is an approximation of `websearch_to_tsquery` without stemming or
+// stop words, which is used by the MemoryModel.
+func (s *TextSearch) textSearchTerms() (alternatives [][]string, excluded []string) {
+	var terms []string
+	for _, word := range strings.Fields(s.Query) {
+		switch {
+		case strings.EqualFold(word, "or"):
+			if len(terms) > 0 {
+				alternatives = append(alternatives, terms)
+				terms = nil
+			}
+		case strings.HasPrefix(word, "-"):
+			excluded = append(excluded, textSearchWords(word)...)
+		default:
+			terms = append(terms, textSearchWords(word)...)
+		}
+	}
+	if len(terms) > 0 {
+		alternatives = append(alternatives, terms)
+	}
+	return alternatives, excluded
+}
@@ -0,0 +1,167 @@
+package surf
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// Executor is the interface that defines what a model needs to
+// run its queries.
+//
+// This is satisfied by a *sql.DB, a *sql.Tx and a *Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Tx is a transaction that is managed by WithTx
+//
+// A Tx may be used as the Database of any model, and may be passed back
+// into WithTx to create a nested transaction.  Commit and Rollback are
+// handled by WithTx, and should not be called directly.
+type Tx struct {
+	*sql.Tx
+	db    *sql.DB
+	depth int
+	state *txState
+}
+
+// txState is the state that a transaction shares with its savepoints
+type txState struct {
+	mutex   sync.Mutex
+	dialect Dialect
+}
+
+// newTx returns a Tx of sqlTx, which was begun on db
+func newTx(sqlTx *sql.Tx, db *sql.DB) *Tx {
+	return &Tx{Tx: sqlTx, db: db, state: &txState{}}
+}
+
+// setDialect records dialect as the Dialect of the models that run on t
+func (t *Tx) setDialect(dialect Dialect) {
+	if t.state == nil {
+		return
+	}
+	t.state.mutex.Lock()
+	defer t.state.mutex.Unlock()
+	t.state.dialect = dialect
+}
+
+// dialect returns the Dialect of the models that run on t, which is a
+// PostgresDialect until a model has run on t
+func (t *Tx) dialect() Dialect {
+	if t.state == nil {
+		return PostgresDialect{}
+	}
+	t.state.mutex.Lock()
+	defer t.state.mutex.Unlock()
+	if t.state.dialect == nil {
+		return PostgresDialect{}
+	}
+	return t.state.dialect
+}
+
+// txContextKey is the context key that a *Tx is stored under
+type txContextKey struct{}
+
+// WithTx runs fn inside of a transaction, committing the transaction if fn
+// returns nil, and rolling it back if fn returns an error or panics.
+//
+// db may be a *sql.DB, which begins a new transaction, or a *sql.Tx / *Tx,
+// in which case fn is run inside of a SAVEPOINT of that transaction.
+func WithTx(db Executor, fn func(*Tx) error) error {
+	return WithTxContext(context.Background(), db, fn)
+}
+
+// WithTxContext runs fn inside of a transaction that is bound to ctx
+//
+// See WithTx for details.
+func WithTxContext(ctx context.Context, db Executor, fn func(*Tx) error) error {
+	switch tv := db.(type) {
+	case *Tx:
+		return tv.savepoint(ctx, fn)
+	case *sql.Tx:
+		return newTx(tv, nil).savepoint(ctx, fn)
+	case *sql.DB:
+		sqlTx, err := tv.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		tx := newTx(sqlTx, tv)
+		return runTx(tx, fn, sqlTx.Commit, func() { sqlTx.Rollback() })
+	}
+	return errors.New("WithTx requires a *sql.DB, *sql.Tx or *surf.Tx")
+}
+
+// savepoint runs fn inside of a SAVEPOINT of the transaction.
+//
+// The savepoint is spelled by the Dialect of the models that have run on
+// the transaction, which is a PostgresDialect until a model has run on it.
+func (t *Tx) savepoint(ctx context.Context, fn func(*Tx) error) error {
+	nested := &Tx{Tx: t.Tx, db: t.db, depth: t.depth + 1, state: t.state}
+	name := "surf_savepoint_" + strconv.Itoa(nested.depth)
+	dialect := t.dialect()
+
+	err := t.exec(ctx, dialect, dialect.Savepoint(name))
+	if err != nil {
+		return err
+	}
+	return runTx(nested, fn, func() error {
+		return t.exec(ctx, dialect, dialect.ReleaseSavepoint(name))
+	}, func() {
+		t.exec(ctx, dialect, dialect.RollbackToSavepoint(name))
+		t.exec(ctx, dialect, dialect.ReleaseSavepoint(name))
+	})
+}
+
+// exec logs and executes a query of dialect that has no values,
+// doing nothing if the query is empty
+func (t *Tx) exec(ctx context.Context, dialect Dialect, query string) error {
+	if query == "" {
+		return nil
+	}
+	printQuery(dialect, query)
+	_, err := t.ExecContext(ctx, query)
+	return err
+}
+
+// runTx calls fn with tx, and then calls commit or rollback depending
+// on the outcome of fn
+func runTx(tx *Tx, fn func(*Tx) error, commit func() error, rollback func()) error {
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+	}()
+	err := fn(tx)
+	if err != nil {
+		rollback()
+		return err
+	}
+	return commit()
+}
+
+// executor returns the Executor that a query of dialect bound to ctx should
+// run against, along with the context to pass along to any foreign references.
+//
+// In the event database is a *Tx, the *Tx is stored on the context, so that
+// foreign references built on the *sql.DB of the *Tx will also use the *Tx.
+// The *Tx records dialect, which its SAVEPOINT statements are spelled with.
+func executor(ctx context.Context, database Executor, dialect Dialect) (context.Context, Executor) {
+	if tx, ok := database.(*Tx); ok {
+		tx.setDialect(dialect)
+		return context.WithValue(ctx, txContextKey{}, tx), tx
+	}
+	if tx, ok := ctx.Value(txContextKey{}).(*Tx); ok && tx.db != nil {
+		if db, ok := database.(*sql.DB); ok && db == tx.db {
+			tx.setDialect(dialect)
+			return ctx, tx
+		}
+	}
+	return ctx, database
+}
@@ -0,0 +1,54 @@
+package surf
+
+import (
+	"errors"
+)
+
+// UpsertAction is an enumeration of the ways an upsert can resolve
+// a conflict with an existing row
+type UpsertAction int
+
+const (
+	UPSERT_DO_UPDATE  UpsertAction = iota // Update the Insertable + Updatable fields of the existing row
+	UPSERT_DO_NOTHING                     // Leave the existing row as it is
+)
+
+// UpsertConfig is the configuration of a Model.Upsert()
+type UpsertConfig struct {
+	// ConflictFields are the names of the fields that make up the conflict
+	// target, which must have a unique constraint in the database.
+	//
+	// This defaults to the first UniqueIdentifier field that is Insertable.
+	ConflictFields []string
+	Action         UpsertAction
+}
+
+// conflictFields returns the fields of config that make up the conflict target
+func (c UpsertConfig) conflictFields(config *Configuration) ([]Field, error) {
+	// Default to the first Insertable UniqueIdentifier
+	if len(c.ConflictFields) == 0 {
+		for _, field := range config.Fields {
+			if field.UniqueIdentifier && field.Insertable {
+				return []Field{field}, nil
+			}
+		}
+		return nil, errors.New("An upsert requires ConflictFields, or a UniqueIdentifier field that is Insertable")
+	}
+
+	// Find each of the ConflictFields
+	var conflictFields []Field
+	for _, name := range c.ConflictFields {
+		found := false
+		for _, field := range config.Fields {
+			if field.Name == name {
+				conflictFields = append(conflictFields, field)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, invalidColumn("upsert", config, name)
+		}
+	}
+	return conflictFields, nil
+}